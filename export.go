@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gi0cann/pandushi/fuzzer"
+	"github.com/gi0cann/pandushi/report"
+)
+
+// Exit codes of the export command
+const (
+	exitOK       = 0
+	exitFindings = 1
+	exitError    = 2
+)
+
+// export writes a stored task in a CI format and returns the exit code reflecting its findings
+func export(storageURI string, project string, name string, format string, output string, failOn string) int {
+	task, err := fuzzer.LoadTaskFromURI(storageURI, project, name)
+	if err != nil {
+		log.Printf("export error: %s\n", err)
+		return exitError
+	}
+
+	switch format {
+	case "junit":
+		if output == "" {
+			output = "pandushi.xml"
+		}
+		err = report.JUnitToFile(output, task, failOn)
+	default:
+		if output == "" {
+			output = "pandushi.sarif"
+		}
+		err = report.SARIFToFile(output, task)
+	}
+	if err != nil {
+		log.Printf("export error: %s\n", err)
+		return exitError
+	}
+	fmt.Printf("%s export written to %s\n", format, output)
+
+	failing := report.FailingFindings(task, failOn)
+	if len(failing) > 0 {
+		fmt.Printf("%d findings with severity %s or higher\n", len(failing), failOn)
+		return exitFindings
+	}
+	return exitOK
+}
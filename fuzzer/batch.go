@@ -0,0 +1,119 @@
+package fuzzer
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/url"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Batch is a group of Tasks of the same project that are run one after the other
+type Batch struct {
+	Project  string
+	Tasks    []*Task
+	Progress *Progress
+}
+
+// SerializedBatch is the serialized version of a Batch stored by file storage
+type SerializedBatch struct {
+	Project string           `bson:"project"`
+	Tasks   []SerializedTask `bson:"tasks"`
+}
+
+// NewBatch returns an empty Batch for project
+func NewBatch(Project string) *Batch {
+	return &Batch{
+		Project:  Project,
+		Progress: NewProgress(),
+	}
+}
+
+// Add adds a Task to the Batch
+func (B *Batch) Add(task *Task) {
+	task.Project = B.Project
+	task.Progress = B.Progress
+	task.sharedProgress = true
+	B.Tasks = append(B.Tasks, task)
+}
+
+// Run runs every Task of the Batch with a combined progress display and stores their results together
+func (B *Batch) Run(TotalThreads int, storageconfig StorageConfig, Proxy *url.URL) {
+	if len(B.Tasks) == 1 {
+		B.Tasks[0].Run(TotalThreads, storageconfig, Proxy)
+		return
+	}
+
+	total := 0
+	for _, task := range B.Tasks {
+		total += len(task.TestCases)
+	}
+	// the total is known before the first Task runs so the percentage and ETA cover the whole Batch
+	B.Progress.Add(total)
+	log.Printf("Running %d tasks with %d test cases\n", len(B.Tasks), total)
+	B.Progress.Start(time.Second)
+	var serializedTasks []SerializedTask
+	for _, task := range B.Tasks {
+		task.Run(TotalThreads, StorageConfig{}, Proxy)
+		serializedTasks = append(serializedTasks, task.serialize())
+	}
+	B.Progress.Stop()
+
+	if storageconfig.UseMongoDB {
+		err := BatchResultsToMongoDB(storageconfig.MongoDBURI, serializedTasks)
+		if err != nil {
+			log.Printf("Batch.Run BatchResultsToMongoDB error: %s\n", err)
+		}
+	}
+
+	if storageconfig.UseFile {
+		err := BatchResultsToFile(storageconfig.FileURI, SerializedBatch{Project: B.Project, Tasks: serializedTasks})
+		if err != nil {
+			log.Printf("Batch.Run BatchResultsToFile error: %s\n", err)
+		}
+	}
+}
+
+// BatchResultsToFile will write the results of every Task of a Batch to a single file
+func BatchResultsToFile(projectName string, batch SerializedBatch) error {
+	fd, err := os.OpenFile(projectName+".json", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	err = json.NewEncoder(fd).Encode(batch)
+	if err != nil {
+		return err
+	}
+	return fd.Close()
+}
+
+// BatchResultsToMongoDB will write the results of every Task of a Batch to MongoDB
+func BatchResultsToMongoDB(mongodbURI string, tasks []SerializedTask) error {
+	mclient, err := mongo.NewClient(options.Client().ApplyURI(mongodbURI))
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	err = mclient.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer mclient.Disconnect(ctx)
+	documents := make([]interface{}, len(tasks))
+	for i := range tasks {
+		documents[i] = tasks[i]
+	}
+	taskCollection := mclient.Database("pandushi").Collection("tasks")
+	result, err := taskCollection.InsertMany(ctx, documents)
+	if err != nil {
+		return err
+	}
+	log.Println(result.InsertedIDs)
+	return nil
+}
@@ -0,0 +1,136 @@
+package fuzzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+func TestBatchRun(t *testing.T) {
+	var mutex sync.Mutex
+	received := make(map[string]int)
+	var batch *Batch
+	firstTotal := int64(-1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		received[r.URL.RawQuery]++
+		if firstTotal < 0 {
+			firstTotal = atomic.LoadInt64(&batch.Progress.total)
+		}
+		mutex.Unlock()
+		w.Write([]byte("hello " + r.URL.Query().Get("foo")))
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	injections := []payloads.Payload{payloads.New("XSS", "<script>alert(1)</script>"), payloads.New("SQLI", "' or 1=1--")}
+	batch = NewBatch("default")
+	var out bytes.Buffer
+	batch.Progress.out = &out
+	for _, path := range []string{"/a", "/b"} {
+		request, err := NewHTTPRequestFromBytes([]byte("GET "+path+"?foo=bar&hello=world HTTP/1.1\r\nHost: "+host+"\r\n\r\n"), false)
+		if err != nil {
+			t.Fatalf("Error creating HTTPRequest: %s\n", err)
+		}
+		batch.Add(&Task{Name: "default", BaseRequest: request, TestCases: request.InjectQueryParameters(injections)})
+	}
+
+	batch.Run(3, StorageConfig{}, nil)
+
+	if len(received) != 4 {
+		t.Errorf("Expected 4 distinct requests got %d\n", len(received))
+	}
+	for query, count := range received {
+		// each injected request is sent once per task
+		if count != 2 {
+			t.Errorf("Expected the request with query %s to be sent twice got %d\n", query, count)
+		}
+	}
+	if firstTotal != 8 {
+		t.Errorf("Expected the progress total of the batch to be 8 before the first task finished got %d\n", firstTotal)
+	}
+	if !strings.Contains(out.String(), "[8/8]") {
+		t.Errorf("Expected the progress to reach 8/8 got %q\n", out.String())
+	}
+	for _, task := range batch.Tasks {
+		if task.Project != "default" || task.Progress != batch.Progress {
+			t.Errorf("Expected the tasks to share the project and progress of the batch\n")
+		}
+		for _, tc := range task.TestCases {
+			if tc.Status != "Done" || tc.Response.Response == nil {
+				t.Errorf("Expected every test case to be done with a response\n")
+			}
+		}
+	}
+}
+
+func TestLoadTaskFromBatchFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pandushi")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	batch := SerializedBatch{Project: "default", Tasks: []SerializedTask{
+		{Name: "scan_10_2020-01-02T03:04:05Z"},
+		{Name: "scan_1x"},
+		{Name: "scan_1_2020-01-02T03:04:05+01:00"},
+		{Name: "other"},
+	}}
+	data, _ := json.Marshal(batch)
+	filename := filepath.Join(dir, "default.json")
+	err = ioutil.WriteFile(filename, data, 0644)
+	if err != nil {
+		t.Fatalf("Error writing batch: %s\n", err)
+	}
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"scan_1", "scan_1_2020-01-02T03:04:05+01:00"},
+		{"scan_10", "scan_10_2020-01-02T03:04:05Z"},
+		{"other", "other"},
+		{"scan", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		task, err := LoadTaskFromFile(filename, test.name)
+		if test.expected == "" {
+			if err != ErrTaskNotFound {
+				t.Errorf("Expected no task named %q got %q (%v)\n", test.name, task.Name, err)
+			}
+			continue
+		}
+		if err != nil || task.Name != test.expected {
+			t.Errorf("Expected the task %s for %q got %q (%v)\n", test.expected, test.name, task.Name, err)
+		}
+	}
+}
+
+func TestResultsToFileRewrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pandushi")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "results")
+	for _, name := range []string{"a much longer scan name", "short"} {
+		err = ResultsToFile(filename, SerializedTask{Name: name})
+		if err != nil {
+			t.Fatalf("Error writing results: %s\n", err)
+		}
+	}
+	task, err := LoadTaskFromFile(filename, "short")
+	if err != nil || task.Name != "short" {
+		t.Errorf("Expected the rewritten task got %q (%v)\n", task.Name, err)
+	}
+}
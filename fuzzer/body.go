@@ -0,0 +1,91 @@
+package fuzzer
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// DefaultMaxBodySize is the number of response body bytes kept when a ClientProfile doesn't set MaxBodySize
+const DefaultMaxBodySize = 10 << 20
+
+// truncationMarker is appended to the text of truncated response bodies
+const truncationMarker = "\n[pandushi: body truncated after %d bytes]"
+
+// BodyHash returns the content address of a response body
+func BodyHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// binaryText is the text standing in for a binary body in ResponseText
+func binaryText(body []byte) string {
+	return fmt.Sprintf("[pandushi: binary body, %d bytes, sha256 %s]", len(body), BodyHash(body))
+}
+
+// StoredBody is a response body stored once per task and referenced by the BodyHash of the test cases
+type StoredBody struct {
+	Hash   string `bson:"hash"`
+	Size   int    `bson:"size"`
+	Binary bool   `bson:"binary,omitempty"`
+	Data   string `bson:"data"` // Text of the body, base64 of the bytes for binary bodies
+}
+
+// splitResponseText returns the status line and headers of a ResponseText, with the blank line, and its body
+func splitResponseText(responseText string) (string, string, bool) {
+	i := strings.Index(responseText, "\r\n\r\n")
+	if i < 0 {
+		return responseText, "", false
+	}
+	return responseText[:i+4], responseText[i+4:], true
+}
+
+// dedupBodies moves the bodies of the serialized test cases into the Bodies of the SerializedTask, once per BodyHash
+func (ST *SerializedTask) dedupBodies(testcases []TestCase) {
+	stored := make(map[string]bool)
+	for i := range ST.TestCases {
+		tc := &ST.TestCases[i]
+		if tc.BodyHash == "" {
+			continue
+		}
+		head, text, ok := splitResponseText(tc.Response)
+		if !ok {
+			continue
+		}
+		if !stored[tc.BodyHash] {
+			body := StoredBody{Hash: tc.BodyHash, Size: len(testcases[i].Response.Body), Binary: tc.Binary, Data: text}
+			if tc.Binary {
+				body.Data = base64.StdEncoding.EncodeToString(testcases[i].Response.Body)
+			}
+			ST.Bodies = append(ST.Bodies, body)
+			stored[tc.BodyHash] = true
+		}
+		tc.Response = head
+	}
+}
+
+// ExpandBodies restores the response text of test cases whose body was moved to the Bodies of the SerializedTask
+func (ST *SerializedTask) ExpandBodies() {
+	bodies := make(map[string]StoredBody)
+	for _, body := range ST.Bodies {
+		bodies[body.Hash] = body
+	}
+	for i := range ST.TestCases {
+		tc := &ST.TestCases[i]
+		body, ok := bodies[tc.BodyHash]
+		if !ok || !strings.HasSuffix(tc.Response, "\r\n\r\n") {
+			continue
+		}
+		if body.Binary {
+			data, err := base64.StdEncoding.DecodeString(body.Data)
+			if err != nil {
+				continue
+			}
+			tc.Response += binaryText(data) + "\r\n"
+		} else {
+			tc.Response += body.Data
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package fuzzer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+func TestResponseSizeCap(t *testing.T) {
+	var bomb bytes.Buffer
+	w := gzip.NewWriter(&bomb)
+	w.Write(make([]byte, 1<<20))
+	w.Close()
+	// a compressed page longer than the cap is decoded before it is truncated
+	var html strings.Builder
+	for i := 0; html.Len() < 1<<16; i++ {
+		fmt.Fprintf(&html, "<p>paragraph %d</p>\n", i*7919%10007)
+	}
+	var page bytes.Buffer
+	w = gzip.NewWriter(&page)
+	w.Write([]byte(html.String()))
+	w.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bomb" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write(bomb.Bytes())
+			return
+		}
+		if r.URL.Path == "/html" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Header().Set("Content-Type", "text/html")
+			w.Write(page.Bytes())
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(bytes.Repeat([]byte("A"), 1<<20))
+	}))
+	defer server.Close()
+
+	profile := NewClientProfile()
+	profile.MaxBodySize = 1000
+	for _, path := range []string{"/large", "/bomb", "/html"} {
+		for _, raw := range []bool{false, true} {
+			req, err := NewHTTPRequestFromBytes([]byte("GET "+path+"?q=1 HTTP/1.1\r\nHost: "+strings.TrimPrefix(server.URL, "http://")+"\r\nConnection: close\r\n\r\n"), false)
+			if err != nil {
+				t.Fatalf("Error creating HTTPRequest: %s\n", err)
+			}
+			req.Raw = raw
+			tc := TestCase{Request: req}
+			tc.send(profile.Client(nil), profile)
+			res := tc.Response
+			if !res.Truncated || len(res.Body) != 1000 || !strings.Contains(res.ResponseText, "[pandushi: body truncated after 1000 bytes]") {
+				t.Errorf("Expected %s with raw %v to be truncated after 1000 bytes got %d bytes\n", path, raw, len(res.Body))
+			}
+			if path == "/html" && (res.Binary || string(res.Body) != html.String()[:1000]) {
+				t.Errorf("Expected the first 1000 decoded bytes of the page with raw %v got %q\n", raw, res.Body)
+			}
+		}
+	}
+}
+
+func TestBinaryBodiesAndDedup(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\xff\xfe")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("img") != "" {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(png)
+			return
+		}
+		w.Write([]byte("same page"))
+	}))
+	defer server.Close()
+
+	base, err := NewHTTPRequestFromBytes([]byte("GET /?a=1&img=1 HTTP/1.1\r\nHost: "+strings.TrimPrefix(server.URL, "http://")+"\r\n\r\n"), false)
+	if err != nil {
+		t.Fatalf("Error creating HTTPRequest: %s\n", err)
+	}
+	var injections []payloads.Payload
+	for _, value := range []string{"x", "y", ""} {
+		injections = append(injections, payloads.New("XSS", value))
+	}
+	task := Task{Name: "bodies", BaseRequest: base, TestCases: base.InjectQueryParameters(injections)}
+	task.Run(2, StorageConfig{}, nil)
+
+	expected := make(map[string]string)
+	binary := 0
+	for _, tc := range task.TestCases {
+		expected[tc.InjectionPoint+tc.Injection] = tc.Response.ResponseText
+		if tc.Response.Binary {
+			binary++
+			if !bytes.Equal(tc.Response.Body, png) || !strings.Contains(tc.Response.ResponseText, binaryText(png)) {
+				t.Errorf("Expected a binary body placeholder got %q\n", tc.Response.ResponseText)
+			}
+		}
+	}
+	// only the empty payload in img returns the page
+	if binary != 5 {
+		t.Errorf("Expected 5 binary responses got %d\n", binary)
+	}
+
+	serialized := task.serialize()
+	if len(serialized.Bodies) != 2 {
+		t.Fatalf("Expected 2 stored bodies got %d\n", len(serialized.Bodies))
+	}
+	for _, tc := range serialized.TestCases {
+		if !strings.HasSuffix(tc.Response, "\r\n\r\n") || tc.BodyHash == "" {
+			t.Errorf("Expected the body to be moved out of the test case got %q\n", tc.Response)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "pandushi")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "bodies")
+	err = ResultsToFile(filename, serialized)
+	if err != nil {
+		t.Fatalf("Error writing results: %s\n", err)
+	}
+	loaded, err := LoadTaskFromFile(filename, "bodies")
+	if err != nil {
+		t.Fatalf("Error loading results: %s\n", err)
+	}
+	for _, tc := range loaded.TestCases {
+		if tc.Response != expected[tc.InjectionPoint+tc.Injection] {
+			t.Errorf("Expected the expanded response %q got %q\n", expected[tc.InjectionPoint+tc.Injection], tc.Response)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package fuzzer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// Redirect policies of a ClientProfile
+const (
+	RedirectFollow = "follow"
+	RedirectNone   = "none"
+	RedirectScope  = "scope"
+)
+
+// RedirectPolicies are the supported redirect policies
+var RedirectPolicies = []string{RedirectFollow, RedirectNone, RedirectScope}
+
+// HTTP versions of a ClientProfile
+const (
+	HTTPVersionAuto = "auto"
+	HTTPVersion11   = "1.1"
+	HTTPVersion2    = "2"
+)
+
+// Duration is a time.Duration read from JSON as a string like "30s" or a number of seconds
+type Duration time.Duration
+
+// UnmarshalJSON reads a Duration from a duration string or a number of seconds
+func (D *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*D = Duration(d)
+		return nil
+	}
+	seconds, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	*D = Duration(seconds * float64(time.Second))
+	return nil
+}
+
+// MarshalJSON writes a Duration as a duration string
+func (D Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(D).String())
+}
+
+// ClientProfile configures the HTTP clients used to check targets, send test cases, log in and run token hooks
+type ClientProfile struct {
+	Timeout             Duration          `json:"timeout"`               // Total time of a request
+	DialTimeout         Duration          `json:"dial_timeout"`          // Time to open a TCP connection
+	TLSHandshakeTimeout Duration          `json:"tls_handshake_timeout"` // Time of the TLS handshake
+	Redirects           string            `json:"redirects"`             // follow, none or scope
+	MaxRedirects        int               `json:"max_redirects"`         // Redirects followed before giving up
+	Scope               []string          `json:"scope"`                 // Hosts like *.example.com followed by the scope policy, the host:port of the request when empty
+	VerifyTLS           bool              `json:"verify_tls"`            // Verify the certificates of the targets
+	ClientCert          string            `json:"client_cert"`           // Optional PEM client certificate file
+	ClientKey           string            `json:"client_key"`            // PEM key file of ClientCert
+	HTTPVersion         string            `json:"http_version"`          // auto, 1.1 or 2 (only h2 is offered over TLS)
+	KeepAlive           bool              `json:"keep_alive"`            // Reuse connections between test cases
+	Resolve             map[string]string `json:"resolve"`               // IP overrides of a host or host:port, like /etc/hosts
+	MaxBodySize         int64             `json:"max_body_size"`         // Response body bytes kept, longer bodies are truncated
+
+	tlsConfig *tls.Config
+}
+
+// NewClientProfile returns the default ClientProfile
+func NewClientProfile() *ClientProfile {
+	return &ClientProfile{
+		Timeout:             Duration(120 * time.Second),
+		DialTimeout:         Duration(30 * time.Second),
+		TLSHandshakeTimeout: Duration(10 * time.Second),
+		Redirects:           RedirectFollow,
+		MaxRedirects:        10,
+		HTTPVersion:         HTTPVersionAuto,
+		MaxBodySize:         DefaultMaxBodySize,
+	}
+}
+
+// NewClientProfileFromFile reads a JSON ClientProfile, missing settings keep their default value
+func NewClientProfileFromFile(filename string) (*ClientProfile, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	profile := NewClientProfile()
+	err = json.Unmarshal(data, profile)
+	if err != nil {
+		return nil, err
+	}
+	return profile, profile.Compile()
+}
+
+// Compile validates the ClientProfile and loads its client certificate
+func (P *ClientProfile) Compile() error {
+	switch P.Redirects {
+	case "":
+		P.Redirects = RedirectFollow
+	case RedirectFollow, RedirectNone, RedirectScope:
+	default:
+		return fmt.Errorf("unknown redirect policy %s", P.Redirects)
+	}
+	switch P.HTTPVersion {
+	case "":
+		P.HTTPVersion = HTTPVersionAuto
+	case HTTPVersionAuto, HTTPVersion11, HTTPVersion2:
+	default:
+		return fmt.Errorf("unknown HTTP version %s", P.HTTPVersion)
+	}
+	config := &tls.Config{InsecureSkipVerify: !P.VerifyTLS}
+	if P.ClientCert != "" {
+		key := P.ClientKey
+		if key == "" {
+			key = P.ClientCert
+		}
+		cert, err := tls.LoadX509KeyPair(P.ClientCert, key)
+		if err != nil {
+			return err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if P.HTTPVersion == HTTPVersion2 {
+		config.NextProtos = []string{"h2"}
+	}
+	P.tlsConfig = config
+	return nil
+}
+
+// TLSConfig returns a copy of the TLS configuration of the ClientProfile
+func (P *ClientProfile) TLSConfig() *tls.Config {
+	if P.tlsConfig == nil {
+		err := P.Compile()
+		if err != nil {
+			fmt.Printf("ClientProfile.TLSConfig error: %s\n", err)
+			return &tls.Config{InsecureSkipVerify: !P.VerifyTLS}
+		}
+	}
+	return P.tlsConfig.Clone()
+}
+
+// resolve returns addr with its host replaced by the Resolve override of the ClientProfile
+func (P *ClientProfile) resolve(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip, ok := P.Resolve[addr]; ok {
+		return net.JoinHostPort(ip, port)
+	}
+	for name, ip := range P.Resolve {
+		if strings.EqualFold(name, host) {
+			return net.JoinHostPort(ip, port)
+		}
+	}
+	return addr
+}
+
+// DialContext opens a TCP connection to addr applying the Resolve overrides
+func (P *ClientProfile) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(P.DialTimeout),
+		KeepAlive: 30 * time.Second,
+	}
+	return dialer.DialContext(ctx, network, P.resolve(addr))
+}
+
+// DialTLS opens a TLS connection to addr applying the Resolve overrides, the certificate is checked against the original host
+func (P *ClientProfile) DialTLS(addr string) (net.Conn, error) {
+	conn, err := P.DialContext(context.Background(), "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return P.handshake(conn, addr)
+}
+
+// handshake runs the TLS handshake of the ClientProfile over conn opened to addr
+func (P *ClientProfile) handshake(conn net.Conn, addr string) (net.Conn, error) {
+	config := P.TLSConfig()
+	config.ServerName, _, _ = net.SplitHostPort(addr)
+	tlsConn := tls.Client(conn, config)
+	if P.TLSHandshakeTimeout > 0 {
+		tlsConn.SetDeadline(time.Now().Add(time.Duration(P.TLSHandshakeTimeout)))
+	}
+	err := tlsConn.Handshake()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+// inScope reports whether the host of a redirect matches one of the scope patterns, or the origin host:port when scope is empty
+func inScope(host string, origin string, scope []string) bool {
+	host = strings.ToLower(host)
+	if len(scope) == 0 {
+		return host == strings.ToLower(origin)
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, pattern := range scope {
+		pattern = strings.ToLower(pattern)
+		if matched, _ := path.Match(pattern, host); matched || pattern == host {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRedirect applies the redirect policy of the ClientProfile and records the followed redirects
+func (P *ClientProfile) checkRedirect(req *http.Request, via []*http.Request) error {
+	switch P.Redirects {
+	case RedirectNone:
+		return http.ErrUseLastResponse
+	case RedirectScope:
+		if !inScope(req.URL.Host, via[0].URL.Host, P.Scope) {
+			return http.ErrUseLastResponse
+		}
+	}
+	if len(via) >= P.MaxRedirects {
+		return fmt.Errorf("stopped after %d redirects", P.MaxRedirects)
+	}
+	recordRedirect(req, P.MaxBodySize)
+	return nil
+}
+
+// RedirectHop is a redirect response followed while sending a request
+type RedirectHop struct {
+	URL      string `bson:"url"`      // URL answered by the redirect
+	Response string `bson:"response"` // Text of the redirect response
+}
+
+// redirectChainKey is the context key of the redirect chain of a request
+type redirectChainKey struct{}
+
+// withRedirectChain returns a copy of req appending the redirects it follows to chain
+func withRedirectChain(req *http.Request, chain *[]RedirectHop) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), redirectChainKey{}, chain))
+}
+
+// recordRedirect appends the redirect answering the previous request of req to its redirect chain, keeping up to max bytes of its body
+func recordRedirect(req *http.Request, max int64) {
+	chain, ok := req.Context().Value(redirectChainKey{}).(*[]RedirectHop)
+	if !ok || req.Response == nil {
+		return
+	}
+	res := req.Response
+	// net/http discards the body of followed redirects
+	body, truncated, _ := readResponseBody(res, max)
+	res.Body.Close()
+	res.Body = ioutil.NopCloser(bytes.NewReader(nil))
+	text, _ := bodyText(body, res.Header.Get("Content-Type"), truncated)
+	*chain = append(*chain, RedirectHop{URL: res.Request.URL.String(), Response: responseHead(res) + "\r\n" + text + "\r\n"})
+}
+
+// Client returns a http.Client configured by the ClientProfile sending its requests through proxy when set
+func (P *ClientProfile) Client(proxy *url.URL) *http.Client {
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           P.DialContext,
+		ForceAttemptHTTP2:     P.HTTPVersion != HTTPVersion11,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   time.Duration(P.TLSHandshakeTimeout),
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       P.TLSConfig(),
+		DisableKeepAlives:     !P.KeepAlive,
+	}
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	switch P.HTTPVersion {
+	case HTTPVersion11:
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	case HTTPVersion2:
+		err := http2.ConfigureTransport(transport)
+		if err != nil {
+			fmt.Printf("ClientProfile.Client http2 error: %s\n", err)
+		}
+		transport.TLSClientConfig.NextProtos = []string{"h2"}
+	}
+	return &http.Client{
+		Timeout:       time.Duration(P.Timeout),
+		Transport:     transport,
+		CheckRedirect: P.checkRedirect,
+	}
+}
+
+// ErrInvalidResolve is returned by ParseResolve for overrides not formatted as host=ip
+var ErrInvalidResolve = errors.New("resolve overrides must look like host=ip or host:port=ip")
+
+// ParseResolve reads host=ip overrides into a Resolve map
+func ParseResolve(overrides []string) (map[string]string, error) {
+	resolve := make(map[string]string)
+	for _, override := range overrides {
+		parts := strings.SplitN(override, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || net.ParseIP(parts[1]) == nil {
+			return nil, ErrInvalidResolve
+		}
+		resolve[parts[0]] = parts[1]
+	}
+	return resolve, nil
+}
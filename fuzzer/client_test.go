@@ -0,0 +1,171 @@
+package fuzzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+func TestDurationUnmarshal(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+	}{
+		{`"30s"`, 30 * time.Second},
+		{`"1m30s"`, 90 * time.Second},
+		{`5`, 5 * time.Second},
+		{`0.5`, 500 * time.Millisecond},
+	}
+	for _, test := range tests {
+		var d Duration
+		err := json.Unmarshal([]byte(test.input), &d)
+		if err != nil || time.Duration(d) != test.expected {
+			t.Errorf("Expected %s for %s got %s (%v)\n", test.expected, test.input, time.Duration(d), err)
+		}
+	}
+}
+
+func TestClientProfile(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	port := server.URL[strings.LastIndex(server.URL, ":"):]
+
+	tests := []struct {
+		profile  ClientProfile
+		url      string
+		status   int
+		protocol string
+		fails    bool
+	}{
+		{ClientProfile{}, "https://target.test" + port + "/", http.StatusOK, "HTTP/2.0", false},
+		{ClientProfile{HTTPVersion: HTTPVersion11}, "https://target.test" + port + "/", http.StatusOK, "HTTP/1.1", false},
+		{ClientProfile{Redirects: RedirectNone}, "https://target.test" + port + "/redirect", http.StatusFound, "", false},
+		{ClientProfile{}, "https://target.test" + port + "/redirect", http.StatusOK, "HTTP/2.0", false},
+		{ClientProfile{VerifyTLS: true}, "https://target.test" + port + "/", 0, "", true},
+	}
+	for _, test := range tests {
+		profile := NewClientProfile()
+		profile.HTTPVersion = test.profile.HTTPVersion
+		profile.Redirects = test.profile.Redirects
+		profile.VerifyTLS = test.profile.VerifyTLS
+		profile.Resolve = map[string]string{"target.test": "127.0.0.1"}
+		err := profile.Compile()
+		if err != nil {
+			t.Fatalf("Error compiling profile: %s\n", err)
+		}
+		resp, err := profile.Client(nil).Get(test.url)
+		if test.fails {
+			if err == nil {
+				t.Errorf("Expected %s to fail with %+v\n", test.url, test.profile)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Error requesting %s with %+v: %s\n", test.url, test.profile, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status || (test.protocol != "" && resp.Proto != test.protocol) {
+			t.Errorf("Expected %d %s for %s with %+v got %d %s\n", test.status, test.protocol, test.url, test.profile, resp.StatusCode, resp.Proto)
+		}
+	}
+}
+
+func TestRedirectPolicies(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("other"))
+	}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/start":
+			http.Redirect(w, r, "/next?to="+url.QueryEscape(r.URL.Query().Get("to")), http.StatusFound)
+		case "/next":
+			http.Redirect(w, r, other.URL+"/"+r.URL.Query().Get("to"), http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		policy string
+		status int
+		hops   int
+	}{
+		{RedirectFollow, http.StatusOK, 2},
+		{RedirectScope, http.StatusFound, 1},
+		{RedirectNone, http.StatusFound, 0},
+	}
+	for _, test := range tests {
+		base, err := NewHTTPRequestFromBytes([]byte("GET /start?to=home HTTP/1.1\r\nHost: "+strings.TrimPrefix(server.URL, "http://")+"\r\n\r\n"), false)
+		if err != nil {
+			t.Fatalf("Error creating HTTPRequest: %s\n", err)
+		}
+		profile := NewClientProfile()
+		profile.Redirects = test.policy
+		task := Task{Name: "redirects", BaseRequest: base, TestCases: base.InjectQueryParameters([]payloads.Payload{payloads.New("XSS", "evil")}), Client: profile}
+		task.Run(1, StorageConfig{}, nil)
+
+		tc := task.TestCases[0]
+		if tc.Response.Response == nil || tc.Response.Response.StatusCode != test.status || len(tc.Redirects) != test.hops {
+			t.Errorf("Expected status %d and %d redirects with %s got %+v %d\n", test.status, test.hops, test.policy, tc.Response.Response, len(tc.Redirects))
+			continue
+		}
+		if test.hops > 0 && (!strings.HasPrefix(tc.Redirects[0].URL, server.URL+"/start?to=evil") || !strings.Contains(tc.Redirects[0].Response, "/next?to=evil")) {
+			t.Errorf("Expected the first redirect to be recorded got %+v\n", tc.Redirects[0])
+		}
+		if serialized := tc.Serialize(); len(serialized.Redirects) != test.hops {
+			t.Errorf("Expected %d serialized redirects got %d\n", test.hops, len(serialized.Redirects))
+		}
+		reflected := false
+		for _, f := range task.Findings {
+			reflected = reflected || f.VulnerabilityClass == "REDIRECT_REFLECTION"
+		}
+		if !reflected {
+			t.Errorf("Expected a REDIRECT_REFLECTION finding with %s got %+v\n", test.policy, task.Findings)
+		}
+	}
+}
+
+func TestRedirectBodyCap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/start" {
+			w.Header().Set("Location", "/end")
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusFound)
+			w.Write(bytes.Repeat([]byte("A"), 1<<20))
+			return
+		}
+		w.Write([]byte("end"))
+	}))
+	defer server.Close()
+
+	base, err := NewHTTPRequestFromBytes([]byte("GET /start?q=1 HTTP/1.1\r\nHost: "+strings.TrimPrefix(server.URL, "http://")+"\r\n\r\n"), false)
+	if err != nil {
+		t.Fatalf("Error creating HTTPRequest: %s\n", err)
+	}
+	profile := NewClientProfile()
+	profile.MaxBodySize = 100
+	tc := TestCase{Request: base}
+	tc.send(profile.Client(nil), profile)
+	if len(tc.Redirects) != 1 || !strings.Contains(tc.Redirects[0].Response, strings.Repeat("A", 100)+"\n[pandushi: body truncated after 100 bytes]") {
+		t.Fatalf("Expected the redirect body to be truncated after 100 bytes got %+v\n", tc.Redirects)
+	}
+	if len(tc.Redirects[0].Response) > 1000 {
+		t.Errorf("Expected a capped redirect response got %d bytes\n", len(tc.Redirects[0].Response))
+	}
+}
@@ -0,0 +1,149 @@
+package fuzzer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/net/html/charset"
+)
+
+// DecodeBody removes the Content-Encoding of a response body. Encodings listed like "gzip, br" are removed in reverse order
+func DecodeBody(body []byte, contentEncoding string) ([]byte, error) {
+	body, _, err := decodeBody(body, contentEncoding, 0)
+	return body, err
+}
+
+// decodeBody is DecodeBody stopping after max decoded bytes when max is positive, it reports whether the body was truncated
+func decodeBody(body []byte, contentEncoding string, max int64) ([]byte, bool, error) {
+	reader, closeDecoders, err := decodeReader(bytes.NewReader(body), contentEncoding)
+	if err != nil {
+		return body, false, err
+	}
+	defer closeDecoders()
+	decoded, truncated, err := readLimited(reader, max)
+	if err != nil {
+		return body, false, err
+	}
+	return decoded, truncated, nil
+}
+
+// decodeReader returns a reader removing the Content-Encoding of reader as it is read, closeDecoders releases the decoders
+func decodeReader(reader io.Reader, contentEncoding string) (decoded io.Reader, closeDecoders func(), err error) {
+	var closers []func()
+	closeDecoders = func() {
+		for _, closeDecoder := range closers {
+			closeDecoder()
+		}
+	}
+	encodings := strings.Split(contentEncoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch strings.ToLower(strings.TrimSpace(encodings[i])) {
+		case "", "identity":
+			continue
+		case "gzip", "x-gzip":
+			reader, err = gzip.NewReader(reader)
+		case "deflate":
+			// deflate is meant to be zlib wrapped but some servers send raw deflate
+			buffered := bufio.NewReader(reader)
+			header, _ := buffered.Peek(2)
+			if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+				reader, err = zlib.NewReader(buffered)
+			} else {
+				reader = flate.NewReader(buffered)
+			}
+		case "br":
+			reader = brotli.NewReader(reader)
+		case "zstd":
+			var decoder *zstd.Decoder
+			decoder, err = zstd.NewReader(reader)
+			if err == nil {
+				closers = append(closers, decoder.Close)
+				reader = decoder
+			}
+		default:
+			err = fmt.Errorf("unsupported Content-Encoding %s", encodings[i])
+		}
+		if err != nil {
+			closeDecoders()
+			return nil, func() {}, err
+		}
+	}
+	return reader, closeDecoders, nil
+}
+
+// cappedBuffer keeps the first max bytes written to it, every byte when max isn't positive
+type cappedBuffer struct {
+	bytes.Buffer
+	max int64
+}
+
+func (B *cappedBuffer) Write(p []byte) (int, error) {
+	if B.max > 0 && int64(B.Len())+int64(len(p)) > B.max {
+		B.Buffer.Write(p[:B.max-int64(B.Len())])
+		return len(p), nil
+	}
+	return B.Buffer.Write(p)
+}
+
+// readLimited reads reader up to max bytes when max is positive and reports whether there was more to read
+func readLimited(reader io.Reader, max int64) ([]byte, bool, error) {
+	if max <= 0 {
+		body, err := ioutil.ReadAll(reader)
+		return body, false, err
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(reader, max+1))
+	if int64(len(body)) > max {
+		return body[:max], true, err
+	}
+	return body, false, err
+}
+
+// IsTextContent reports whether a Content-Type is text that can be converted to UTF-8.
+// Responses without a Content-Type are text when their body is valid UTF-8.
+func IsTextContent(contentType string, body []byte) bool {
+	if contentType == "" {
+		return utf8.Valid(body)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return utf8.Valid(body)
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	for _, suffix := range []string{"json", "xml", "javascript", "ecmascript", "x-www-form-urlencoded", "html"} {
+		if strings.HasSuffix(mediaType, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ToUTF8 converts a text body to UTF-8 using the charset of its Content-Type, meta tags or byte order mark.
+// Binary bodies are returned unmodified.
+func ToUTF8(body []byte, contentType string) []byte {
+	if !IsTextContent(contentType, body) {
+		return body
+	}
+	encoding, name, _ := charset.DetermineEncoding(body, contentType)
+	if name == "utf-8" || (name == "windows-1252" && utf8.Valid(body)) {
+		// DetermineEncoding falls back to windows-1252, keep bodies that are already valid UTF-8
+		return body
+	}
+	decoded, err := ioutil.ReadAll(encoding.NewDecoder().Reader(bytes.NewReader(body)))
+	if err != nil {
+		return body
+	}
+	return decoded
+}
@@ -0,0 +1,99 @@
+package fuzzer
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func compress(t *testing.T, data []byte, writer func(io.Writer) io.WriteCloser) []byte {
+	var buffer bytes.Buffer
+	w := writer(&buffer)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Error compressing: %s\n", err)
+	}
+	w.Close()
+	return buffer.Bytes()
+}
+
+func TestDecodeBody(t *testing.T) {
+	plain := []byte("<script>alert(1)</script>")
+	gzipWriter := func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }
+	zlibWriter := func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }
+	flateWriter := func(w io.Writer) io.WriteCloser { fw, _ := flate.NewWriter(w, flate.DefaultCompression); return fw }
+	brotliWriter := func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) }
+	zstdWriter := func(w io.Writer) io.WriteCloser { zw, _ := zstd.NewWriter(w); return zw }
+
+	tests := []struct {
+		encoding string
+		body     []byte
+	}{
+		{"", plain},
+		{"identity", plain},
+		{"gzip", compress(t, plain, gzipWriter)},
+		{"deflate", compress(t, plain, zlibWriter)},
+		{"deflate", compress(t, plain, flateWriter)},
+		{"br", compress(t, plain, brotliWriter)},
+		{"zstd", compress(t, plain, zstdWriter)},
+		{"gzip, br", compress(t, compress(t, plain, gzipWriter), brotliWriter)},
+	}
+	for _, test := range tests {
+		got, err := DecodeBody(test.body, test.encoding)
+		if err != nil || !bytes.Equal(got, plain) {
+			t.Errorf("Expected %q for %s got %q (%v)\n", plain, test.encoding, got, err)
+		}
+	}
+	if _, err := DecodeBody(plain, "compress"); err == nil {
+		t.Errorf("Expected an error for an unsupported Content-Encoding\n")
+	}
+}
+
+func TestToUTF8(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\xe9")
+	tests := []struct {
+		body        []byte
+		contentType string
+		expected    []byte
+	}{
+		{[]byte("caf\xe9"), "text/html; charset=iso-8859-1", []byte("café")},
+		{[]byte("caf\xc3\xa9"), "text/html; charset=utf-8", []byte("café")},
+		{[]byte("<meta charset=\"windows-1251\">\xcf\xf0\xe8\xe2\xe5\xf2"), "text/html", []byte(`<meta charset="windows-1251">Привет`)},
+		{[]byte("{\"a\":\"caf\xe9\"}"), "application/json; charset=latin1", []byte(`{"a":"café"}`)},
+		{[]byte("caf\xc3\xa9"), "text/plain", []byte("café")},
+		{png, "image/png", png},
+		{png, "", png},
+	}
+	for _, test := range tests {
+		if got := ToUTF8(test.body, test.contentType); !bytes.Equal(got, test.expected) {
+			t.Errorf("Expected %q for %s got %q\n", test.expected, test.contentType, got)
+		}
+	}
+}
+
+func TestNewHTTPResponseDecoding(t *testing.T) {
+	body := compress(t, []byte("caf\xe9 <b>x</b>"), func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) })
+	resp := &http.Response{
+		Proto:  "HTTP/1.1",
+		Status: "200 OK",
+		Header: http.Header{"Content-Encoding": {"br"}, "Content-Type": {"text/html; charset=iso-8859-1"}},
+		Body:   ioutil.NopCloser(bytes.NewReader(body)),
+	}
+	res, err := NewHTTPResponse(resp)
+	if err != nil {
+		t.Fatalf("Error creating HTTPResponse: %s\n", err)
+	}
+	if responseBody(res.ResponseText) != "café <b>x</b>\r\n" {
+		t.Errorf("Expected a decoded UTF-8 body got %q\n", res.ResponseText)
+	}
+	if string(res.Body) != "caf\xe9 <b>x</b>" {
+		t.Errorf("Expected the decoded bytes before charset conversion got %q\n", res.Body)
+	}
+}
@@ -0,0 +1,94 @@
+package fuzzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+// InjectionEncoders encode payloads for the injection point types they are injected in so the injected requests stay valid
+var InjectionEncoders = map[string]func(string) string{
+	"query":                 url.QueryEscape,
+	"x-www-form-urlencoded": url.QueryEscape,
+	"path":                  url.PathEscape,
+	"json":                  jsonEscape,
+	"headers":               headerEscape,
+}
+
+// EncodeInjection returns value encoded for an injection point type, value is returned unmodified when raw is set or the type has no encoder
+func EncodeInjection(injectionPointType string, value string, raw bool) string {
+	encoder, ok := InjectionEncoders[injectionPointType]
+	if raw || !ok {
+		return value
+	}
+	return encoder(value)
+}
+
+// urlEncodedContexts are the injection point types whose values are url-encoded
+var urlEncodedContexts = []string{"query", "x-www-form-urlencoded", "path"}
+
+// injectsRaw reports whether injection is written as is in an injection point type by req: raw injections,
+// and payloads whose Pipeline ends with a url encoding injected in a url-encoded context, aren't encoded again
+func (req *HTTPRequest) injectsRaw(injection payloads.Payload, injectionPointType string) bool {
+	if req.RawInjection {
+		return true
+	}
+	pipeline, _ := payloads.ParsePipeline(injection.Pipeline)
+	if len(pipeline) == 0 || !arrayContains(urlEncodedContexts, injectionPointType) {
+		return false
+	}
+	last := pipeline[len(pipeline)-1]
+	return last == "url" || last == "double-url"
+}
+
+// jsonEscape escapes value to be written inside of a JSON string
+func jsonEscape(value string) string {
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	if encoder.Encode(value) != nil {
+		return value
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSuffix(encoded.String(), "\n"), `"`), `"`)
+}
+
+// headerEscape percent-encodes the control characters of value, which can't be written in a header value, tabs are kept
+func headerEscape(value string) string {
+	var encoded strings.Builder
+	for i := 0; i < len(value); i++ {
+		if (value[i] < 0x20 && value[i] != '\t') || value[i] == 0x7f {
+			fmt.Fprintf(&encoded, "%%%02X", value[i])
+		} else {
+			encoded.WriteByte(value[i])
+		}
+	}
+	return encoded.String()
+}
+
+// encodeValues returns values with key set to value in the url-encoded form of url.Values.Encode.
+// value is encoded for injectionPointType, or written as is when raw is set.
+func encodeValues(values url.Values, key string, value string, injectionPointType string, raw bool) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var encoded strings.Builder
+	for _, k := range keys {
+		if encoded.Len() > 0 {
+			encoded.WriteByte('&')
+		}
+		encoded.WriteString(url.QueryEscape(k) + "=")
+		if k == key {
+			encoded.WriteString(EncodeInjection(injectionPointType, value, raw))
+		} else {
+			encoded.WriteString(url.QueryEscape(strings.Join(values[k], "")))
+		}
+	}
+	return encoded.String()
+}
@@ -0,0 +1,143 @@
+package fuzzer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+func TestEncodeInjection(t *testing.T) {
+	tests := []struct {
+		injectionPointType string
+		value              string
+		raw                bool
+		expected           string
+	}{
+		{"query", "' or 1=1--", false, "%27+or+1%3D1--"},
+		{"x-www-form-urlencoded", "a&b=c", false, "a%26b%3Dc"},
+		{"path", "../etc/passwd", false, "..%2Fetc%2Fpasswd"},
+		{"json", `"}, "admin": true, "x": "<b>`, false, `\"}, \"admin\": true, \"x\": \"<b>`},
+		{"headers", "x\r\nInjected: 1\t2", false, "x%0D%0AInjected: 1\t2"},
+		{"marked", "' or 1=1--", false, "' or 1=1--"},
+		{"json", `"`, true, `"`},
+		{"path", "../etc/passwd", true, "../etc/passwd"},
+	}
+	for _, test := range tests {
+		encoded := EncodeInjection(test.injectionPointType, test.value, test.raw)
+		if encoded != test.expected {
+			t.Errorf("Expected %s to be encoded as %s in %s (raw %t) got %s\n", test.value, test.expected, test.injectionPointType, test.raw, encoded)
+		}
+	}
+}
+
+func TestInjectionEncoding(t *testing.T) {
+	tests := []struct {
+		request  string
+		inject   func(req *HTTPRequest, injections []payloads.Payload) []TestCase
+		raw      bool
+		sentRaw  bool
+		expected string
+	}{
+		{"GET /a?foo=bar HTTP/1.1\r\nHost: example.com\r\n\r\n", (*HTTPRequest).InjectQueryParameters, false, false, "GET /a?foo=%22+a%26b%3D1 HTTP/1.1"},
+		{"GET /a?foo=bar HTTP/1.1\r\nHost: example.com\r\n\r\n", (*HTTPRequest).InjectQueryParameters, true, false, "GET /a?foo=\" a&b=1 HTTP/1.1"},
+		{"GET /a HTTP/1.1\r\nHost: example.com\r\n\r\n", (*HTTPRequest).InjectPath, false, false, "GET /%22%20a&b=1? HTTP/1.1"},
+		{"GET /a HTTP/1.1\r\nHost: example.com\r\n\r\n", (*HTTPRequest).InjectPath, true, true, "GET /\" a&b=1? HTTP/1.1"},
+		{"POST /a HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/json\r\nContent-Length: 13\r\n\r\n{\"foo\":\"bar\"}", (*HTTPRequest).InjectJSONParameters, false, false, `{"foo":"\" a&b=1"}`},
+		{"POST /a HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/json\r\nContent-Length: 13\r\n\r\n{\"foo\":\"bar\"}", (*HTTPRequest).InjectJSONParameters, true, false, `{"foo":"" a&b=1"}`},
+		{"POST /a HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 7\r\n\r\nfoo=bar", (*HTTPRequest).InjectFormURLEncodedBody, false, false, "foo=%22+a%26b%3D1"},
+		{"POST /a HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 7\r\n\r\nfoo=bar", (*HTTPRequest).InjectFormURLEncodedBody, true, false, "foo=\" a&b=1"},
+	}
+	for _, test := range tests {
+		req, err := NewHTTPRequestFromBytes([]byte(test.request), false)
+		if err != nil {
+			t.Fatalf("Error creating HTTPRequest: %s\n", err)
+		}
+		req.RawInjection = test.raw
+		testcases := test.inject(&req, []payloads.Payload{payloads.New("TEST", `" a&b=1`)})
+		if len(testcases) != 1 {
+			t.Fatalf("Expected 1 test case for %q got %d\n", test.request, len(testcases))
+		}
+		tc := testcases[0]
+		if !strings.Contains(tc.Request.RequestText, test.expected) {
+			t.Errorf("Expected %s in the %s test case (raw %t) got %q\n", test.expected, tc.InjectionPointType, test.raw, tc.Request.RequestText)
+		}
+		if tc.Request.Raw != test.sentRaw {
+			t.Errorf("Expected the %s test case (raw %t) to be sent raw %t\n", tc.InjectionPointType, test.raw, test.sentRaw)
+		}
+		if tc.InjectionPointType == "json" && !test.raw {
+			body, _ := ioutil.ReadAll(tc.Request.Request.Body)
+			var decoded map[string]string
+			if err := json.Unmarshal(body, &decoded); err != nil || decoded["foo"] != `" a&b=1` {
+				t.Errorf("Expected a valid JSON body with the payload got %s (%v)\n", body, err)
+			}
+		}
+	}
+}
+
+func TestInjectHeadersEncoding(t *testing.T) {
+	req, err := NewHTTPRequestFromBytes([]byte("GET / HTTP/1.1\r\nHost: example.com\r\nX-Test: a\r\n\r\n"), false)
+	if err != nil {
+		t.Fatalf("Error creating HTTPRequest: %s\n", err)
+	}
+	for _, raw := range []bool{false, true} {
+		req.RawInjection = raw
+		testcases := req.InjectHeaders([]payloads.Payload{payloads.New("CRLF", "x\r\nInjected: 1")})
+		if len(testcases) != 1 {
+			t.Fatalf("Expected 1 test case got %d\n", len(testcases))
+		}
+		injected := strings.Contains(testcases[0].Request.RequestText, "\r\nInjected: 1\r\n")
+		if injected != raw || testcases[0].Request.Raw != raw {
+			t.Errorf("Expected the header injection to be raw %t got %q\n", raw, testcases[0].Request.RequestText)
+		}
+	}
+}
+
+func TestPipelinePayloadsAreNotEncodedAgain(t *testing.T) {
+	req, err := NewHTTPRequestFromBytes([]byte("GET /a/b?foo=bar HTTP/1.1\r\nHost: example.com\r\n\r\n"), false)
+	if err != nil {
+		t.Fatalf("Error creating HTTPRequest: %s\n", err)
+	}
+	injection := payloads.New("XSS", "%3Cscript%3E")
+	injection.Original = "<script>"
+	injection.Pipeline = "url"
+	testcases := append(req.InjectQueryParameters([]payloads.Payload{injection}), req.InjectPath([]payloads.Payload{injection})...)
+	expected := []string{"GET /a/b?foo=%3Cscript%3E HTTP/1.1", "GET /%3Cscript%3E/b?foo=bar HTTP/1.1", "GET /a/%3Cscript%3E?foo=bar HTTP/1.1"}
+	if len(testcases) != len(expected) {
+		t.Fatalf("Expected %d test cases got %d\n", len(expected), len(testcases))
+	}
+	for i, tc := range testcases {
+		if !strings.HasPrefix(tc.Request.RequestText, expected[i]) || tc.Request.Raw {
+			t.Errorf("Expected %s sent with net/http got %q (raw %t)\n", expected[i], tc.Request.RequestText, tc.Request.Raw)
+		}
+	}
+}
+
+func TestPipelinePayloadsStayValid(t *testing.T) {
+	injection := payloads.New("XSS", `">&#60;&#115;&#62;`)
+	injection.Original = "<s>"
+	injection.Pipeline = `html-entity|prefix:">`
+	query, err := NewHTTPRequestFromBytes([]byte("GET /a?foo=bar HTTP/1.1\r\nHost: example.com\r\n\r\n"), false)
+	if err != nil {
+		t.Fatalf("Error creating HTTPRequest: %s\n", err)
+	}
+	testcases := query.InjectQueryParameters([]payloads.Payload{injection})
+	if len(testcases) != 1 || testcases[0].Request.Request.URL.Query().Get("foo") != injection.Value {
+		t.Errorf("Expected the query to read back the processed payload got %+v\n", testcases)
+	}
+	body, err := NewHTTPRequestFromBytes([]byte("POST /a HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/json\r\nContent-Length: 13\r\n\r\n{\"foo\":\"bar\"}"), false)
+	if err != nil {
+		t.Fatalf("Error creating HTTPRequest: %s\n", err)
+	}
+	testcases = body.InjectJSONParameters([]payloads.Payload{injection})
+	if len(testcases) != 1 {
+		t.Fatalf("Expected 1 JSON test case got %d\n", len(testcases))
+	}
+	sent, _ := ioutil.ReadAll(testcases[0].Request.Request.Body)
+	var decoded map[string]string
+	if err := json.Unmarshal(sent, &decoded); err != nil || decoded["foo"] != injection.Value {
+		t.Errorf("Expected a valid JSON body with the processed payload got %s (%v)\n", sent, err)
+	}
+}
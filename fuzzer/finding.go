@@ -0,0 +1,336 @@
+package fuzzer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+// Finding severities ordered from least to most severe
+const (
+	SeverityInfo     = "INFO"
+	SeverityLow      = "LOW"
+	SeverityMedium   = "MEDIUM"
+	SeverityHigh     = "HIGH"
+	SeverityCritical = "CRITICAL"
+)
+
+// Severities is the list of supported finding severities ordered from least to most severe
+var Severities = []string{
+	SeverityInfo,
+	SeverityLow,
+	SeverityMedium,
+	SeverityHigh,
+	SeverityCritical,
+}
+
+// Finding confidence levels ordered from weakest to strongest
+const (
+	ConfidenceTentative = "TENTATIVE"
+	ConfidenceFirm      = "FIRM"
+	ConfidenceCertain   = "CERTAIN"
+)
+
+// evidenceContext is the number of bytes kept on each side of a match in an evidence snippet
+const evidenceContext = 40
+
+// maxEvidence is the maximum number of evidence snippets kept per finding
+const maxEvidence = 5
+
+// Finding represents a verdict about a vulnerability supported by one or more TestCases
+type Finding struct {
+	VulnerabilityClass string
+	Severity           string
+	Confidence         string
+	InjectionPoint     string
+	InjectionPointType string
+	Evidence           []string // Snippets of the responses that support the finding
+	TestCases          []int    // Indexes into Task.TestCases of the supporting test cases
+}
+
+// SerializedFinding is the BSON serialized version of Finding
+type SerializedFinding struct {
+	VulnerabilityClass string   `bson:"vulnerabilityclass"`
+	Severity           string   `bson:"severity"`
+	Confidence         string   `bson:"confidence"`
+	InjectionPoint     string   `bson:"injectionpoint,omitempty"`
+	InjectionPointType string   `bson:"injectionpointtype,omitempty"`
+	Evidence           []string `bson:"evidence,omitempty"`
+	TestCases          []int    `bson:"testcases"`
+}
+
+// Serialize returns a serialized version of Finding
+func (F *Finding) Serialize() SerializedFinding {
+	return SerializedFinding{
+		VulnerabilityClass: F.VulnerabilityClass,
+		Severity:           F.Severity,
+		Confidence:         F.Confidence,
+		InjectionPoint:     F.InjectionPoint,
+		InjectionPointType: F.InjectionPointType,
+		Evidence:           F.Evidence,
+		TestCases:          F.TestCases,
+	}
+}
+
+// SeverityRank returns the position of severity in Severities or -1 if it is unknown
+func SeverityRank(severity string) int {
+	for i, s := range Severities {
+		if s == strings.ToUpper(severity) {
+			return i
+		}
+	}
+	return -1
+}
+
+// SQLErrorSignatures are regexes matching database error messages leaked in responses
+var SQLErrorSignatures = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)you have an error in your sql syntax`),
+	regexp.MustCompile(`(?i)warning: mysql`),
+	regexp.MustCompile(`(?i)unclosed quotation mark after the character string`),
+	regexp.MustCompile(`(?i)quoted string not properly terminated`),
+	regexp.MustCompile(`(?i)pg_query\(\): query failed`),
+	regexp.MustCompile(`(?i)syntax error at or near`),
+	regexp.MustCompile(`(?i)sqlite3?\.OperationalError`),
+	regexp.MustCompile(`(?i)SQLSTATE\[`),
+	regexp.MustCompile(`(?i)ORA-[0-9]{5}`),
+}
+
+// Check is a function that inspects a finished TestCase and returns a partial Finding and evidence if it detects something
+type Check func(tc *TestCase) (finding Finding, evidence string, ok bool)
+
+// DefaultChecks are the checks run by Task.Analyze
+var DefaultChecks = []Check{
+	CheckReflection,
+	CheckSQLErrors,
+	CheckServerError,
+	CheckSmuggling,
+	CheckRedirectReflection,
+	CheckExpectedEvidence,
+}
+
+// responseBody returns the body part of a ResponseText
+func responseBody(responseText string) string {
+	if i := strings.Index(responseText, "\r\n\r\n"); i >= 0 {
+		return responseText[i+4:]
+	}
+	return ""
+}
+
+// snippet returns the text surrounding text[start:end]
+func snippet(text string, start int, end int) string {
+	from := start - evidenceContext
+	if from < 0 {
+		from = 0
+	}
+	to := end + evidenceContext
+	if to > len(text) {
+		to = len(text)
+	}
+	return text[from:to]
+}
+
+// CheckReflection reports payloads reflected unmodified in the response body.
+// Processed payloads are also looked for before their Pipeline was applied since the target may decode them.
+// Mutations are left out, their short values like 0 are found in most bodies.
+func CheckReflection(tc *TestCase) (Finding, string, bool) {
+	body := responseBody(tc.Response.ResponseText)
+	if tc.Injection == "" || body == "" || tc.InjectionType == payloads.MutationType {
+		return Finding{}, "", false
+	}
+	reflected := tc.Injection
+	i := strings.Index(body, reflected)
+	if i < 0 && tc.Original != "" {
+		reflected = tc.Original
+		i = strings.Index(body, reflected)
+	}
+	if i < 0 {
+		return Finding{}, "", false
+	}
+	finding := Finding{
+		VulnerabilityClass: "UNENCODED_REFLECTION",
+		Severity:           SeverityInfo,
+		Confidence:         ConfidenceFirm,
+	}
+	if strings.ToUpper(tc.InjectionType) == "XSS" {
+		finding.VulnerabilityClass = "XSS"
+		finding.Severity = SeverityHigh
+	}
+	return finding, snippet(body, i, i+len(reflected)), true
+}
+
+// CheckSQLErrors reports database error messages in the response body
+func CheckSQLErrors(tc *TestCase) (Finding, string, bool) {
+	body := responseBody(tc.Response.ResponseText)
+	for _, signature := range SQLErrorSignatures {
+		if index := signature.FindStringIndex(body); index != nil {
+			return Finding{
+				VulnerabilityClass: "SQLI",
+				Severity:           SeverityHigh,
+				Confidence:         ConfidenceFirm,
+			}, snippet(body, index[0], index[1]), true
+		}
+	}
+	return Finding{}, "", false
+}
+
+// locationHeader returns the Location header line of a ResponseText
+func locationHeader(responseText string) string {
+	head := responseText
+	if i := strings.Index(head, "\r\n\r\n"); i >= 0 {
+		head = head[:i]
+	}
+	for _, line := range strings.Split(head, "\r\n") {
+		if len(line) > 9 && strings.EqualFold(line[:9], "location:") {
+			return line
+		}
+	}
+	return ""
+}
+
+// CheckRedirectReflection reports payloads reflected in the Location header of a redirect, including the redirects followed
+func CheckRedirectReflection(tc *TestCase) (Finding, string, bool) {
+	if tc.Injection == "" {
+		return Finding{}, "", false
+	}
+	responses := []string{tc.Response.ResponseText}
+	for _, hop := range tc.Redirects {
+		responses = append(responses, hop.Response)
+	}
+	for _, response := range responses {
+		location := locationHeader(response)
+		if location != "" && strings.Contains(location[9:], tc.Injection) {
+			return Finding{
+				VulnerabilityClass: "REDIRECT_REFLECTION",
+				Severity:           SeverityMedium,
+				Confidence:         ConfidenceTentative,
+			}, location, true
+		}
+	}
+	return Finding{}, "", false
+}
+
+// compiledPattern is a compiled ExpectedResponse pattern or its compile error
+type compiledPattern struct {
+	pattern *regexp.Regexp
+	err     error
+}
+
+// expectedPatterns caches the compiledPatterns by ExpectedResponse pattern for every Task, whichever goroutine analyzes it
+var expectedPatterns sync.Map
+
+// expectedPattern compiles an ExpectedResponse pattern once, invalid patterns are reported the first time they are compiled
+func expectedPattern(expr string) (*regexp.Regexp, error) {
+	if cached, ok := expectedPatterns.Load(expr); ok {
+		compiled := cached.(compiledPattern)
+		return compiled.pattern, compiled.err
+	}
+	pattern, err := regexp.Compile(expr)
+	if _, loaded := expectedPatterns.LoadOrStore(expr, compiledPattern{pattern, err}); !loaded && err != nil {
+		fmt.Printf("CheckExpectedEvidence error: %s\n", err)
+	}
+	return pattern, err
+}
+
+// CheckExpectedEvidence reports payloads whose expected response or expected delay from their Metadata was observed.
+// The finding has the payload type as class and its Risk as severity, HIGH when not set.
+func CheckExpectedEvidence(tc *TestCase) (Finding, string, bool) {
+	expected := tc.Metadata
+	if tc.Response.Response == nil || (expected.ExpectedResponse == "" && expected.ExpectedDelay <= 0) {
+		return Finding{}, "", false
+	}
+	finding := Finding{
+		VulnerabilityClass: strings.ToUpper(tc.InjectionType),
+		Severity:           SeverityHigh,
+		Confidence:         ConfidenceFirm,
+	}
+	if SeverityRank(expected.Risk) >= 0 {
+		finding.Severity = strings.ToUpper(expected.Risk)
+	}
+	platform := ""
+	if expected.Platform != "" {
+		platform = "[" + expected.Platform + "] "
+	}
+	if expected.ExpectedResponse != "" {
+		pattern, err := expectedPattern(expected.ExpectedResponse)
+		if err != nil {
+			return Finding{}, "", false
+		}
+		body := responseBody(tc.Response.ResponseText)
+		if index := pattern.FindStringIndex(body); index != nil {
+			return finding, platform + snippet(body, index[0], index[1]), true
+		}
+	}
+	// a slow target delays every response, time based findings stay tentative
+	if expected.ExpectedDelay > 0 && tc.Response.Timing.Total >= expected.Delay() {
+		finding.Confidence = ConfidenceTentative
+		return finding, fmt.Sprintf("%sresponse took %s, expected delay %s", platform, tc.Response.Timing.Total, expected.Delay()), true
+	}
+	return Finding{}, "", false
+}
+
+// CheckServerError reports injections that caused the server to return a 5xx status code
+func CheckServerError(tc *TestCase) (Finding, string, bool) {
+	res := tc.Response.Response
+	// smuggling probes are malformed on purpose and often time out at the front-end
+	if res == nil || res.StatusCode < 500 || tc.Smuggling != nil {
+		return Finding{}, "", false
+	}
+	return Finding{
+		VulnerabilityClass: "SERVER_ERROR",
+		Severity:           SeverityLow,
+		Confidence:         ConfidenceTentative,
+	}, res.Proto + " " + res.Status, true
+}
+
+// Analyze runs checks against the finished TestCases and returns the resulting Findings.
+// Findings with the same vulnerability class and injection point are merged together.
+func (T *Task) Analyze(checks []Check) []Finding {
+	var findings []Finding
+	index := make(map[string]int)
+	for i := range T.TestCases {
+		tc := &T.TestCases[i]
+		for _, check := range checks {
+			finding, evidence, ok := check(tc)
+			if !ok {
+				continue
+			}
+			key := finding.VulnerabilityClass + "\x00" + tc.InjectionPointType + "\x00" + tc.InjectionPoint
+			fi, found := index[key]
+			if !found {
+				finding.InjectionPoint = tc.InjectionPoint
+				finding.InjectionPointType = tc.InjectionPointType
+				findings = append(findings, finding)
+				fi = len(findings) - 1
+				index[key] = fi
+			}
+			f := &findings[fi]
+			f.TestCases = append(f.TestCases, i)
+			if evidence != "" && len(f.Evidence) < maxEvidence {
+				f.Evidence = append(f.Evidence, evidence)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return SeverityRank(findings[i].Severity) > SeverityRank(findings[j].Severity)
+	})
+	return findings
+}
+
+// PrintFindingsSummary prints the number of findings per severity followed by each finding
+func PrintFindingsSummary(findings []Finding) {
+	counts := make(map[string]int)
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	fmt.Printf("Findings: %d\n", len(findings))
+	for i := len(Severities) - 1; i >= 0; i-- {
+		fmt.Printf("  %-8s %d\n", Severities[i], counts[Severities[i]])
+	}
+	for _, f := range findings {
+		fmt.Printf("[%s] %s (%s) %s %s - %d test cases\n", f.Severity, f.VulnerabilityClass, f.Confidence, f.InjectionPointType, f.InjectionPoint, len(f.TestCases))
+	}
+}
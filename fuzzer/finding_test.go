@@ -0,0 +1,149 @@
+package fuzzer
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+func TestAnalyze(t *testing.T) {
+	task := Task{
+		TestCases: []TestCase{
+			{
+				Injection:          "<script>alert(1)</script>",
+				InjectionType:      "XSS",
+				InjectionPoint:     "foo",
+				InjectionPointType: "query",
+				Response: HTTPResponse{
+					Response:     &http.Response{StatusCode: 200},
+					ResponseText: "HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n<p>Hello <script>alert(1)</script></p>\r\n",
+				},
+			},
+			{
+				Injection:          "\"><script>alert(1)</script>",
+				InjectionType:      "XSS",
+				InjectionPoint:     "foo",
+				InjectionPointType: "query",
+				Response: HTTPResponse{
+					Response:     &http.Response{StatusCode: 200},
+					ResponseText: "HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n<input value=\"\"><script>alert(1)</script>\">\r\n",
+				},
+			},
+			{
+				Injection:          "' or 1=1--",
+				InjectionType:      "SQLI",
+				InjectionPoint:     "id",
+				InjectionPointType: "query",
+				Response: HTTPResponse{
+					Response:     &http.Response{StatusCode: 500, Status: "500 Internal Server Error", Proto: "HTTP/1.1"},
+					ResponseText: "HTTP/1.1 500 Internal Server Error\r\n\r\nYou have an error in your SQL syntax near ''' at line 1\r\n",
+				},
+			},
+			{
+				Injection:          "<script>alert(1)</script>",
+				InjectionType:      "XSS",
+				InjectionPoint:     "bar",
+				InjectionPointType: "query",
+				Response: HTTPResponse{
+					Response:     &http.Response{StatusCode: 200},
+					ResponseText: "HTTP/1.1 200 OK\r\n\r\n&lt;script&gt;alert(1)&lt;/script&gt;\r\n",
+				},
+			},
+			{
+				Injection:          "%3Cscript%3Ealert%281%29%3C%2Fscript%3E",
+				InjectionType:      "XSS",
+				InjectionPoint:     "baz",
+				InjectionPointType: "query",
+				Original:           "<script>alert(1)</script>",
+				Pipeline:           "url",
+				Response: HTTPResponse{
+					Response:     &http.Response{StatusCode: 200},
+					ResponseText: "HTTP/1.1 200 OK\r\n\r\n<b><script>alert(1)</script></b>\r\n",
+				},
+			},
+		},
+	}
+
+	findings := task.Analyze(DefaultChecks)
+
+	expected := []struct {
+		class     string
+		severity  string
+		point     string
+		testcases []int
+	}{
+		{"XSS", SeverityHigh, "foo", []int{0, 1}},
+		{"SQLI", SeverityHigh, "id", []int{2}},
+		{"XSS", SeverityHigh, "baz", []int{4}},
+		{"SERVER_ERROR", SeverityLow, "id", []int{2}},
+	}
+
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings got %d: %+v\n", len(expected), len(findings), findings)
+	}
+
+	for i, e := range expected {
+		f := findings[i]
+		if f.VulnerabilityClass != e.class || f.Severity != e.severity || f.InjectionPoint != e.point {
+			t.Errorf("Finding %d doesn't match. expected: %s %s %s got: %s %s %s\n", i, e.class, e.severity, e.point, f.VulnerabilityClass, f.Severity, f.InjectionPoint)
+		}
+		if len(f.TestCases) != len(e.testcases) {
+			t.Errorf("Finding %d expected test cases %v got %v\n", i, e.testcases, f.TestCases)
+			continue
+		}
+		for j := range e.testcases {
+			if f.TestCases[j] != e.testcases[j] {
+				t.Errorf("Finding %d expected test cases %v got %v\n", i, e.testcases, f.TestCases)
+			}
+		}
+		if len(f.Evidence) == 0 {
+			t.Errorf("Finding %d has no evidence\n", i)
+		}
+	}
+}
+
+func TestCheckExpectedEvidence(t *testing.T) {
+	tests := []struct {
+		metadata   payloads.Metadata
+		body       string
+		total      time.Duration
+		ok         bool
+		severity   string
+		confidence string
+		evidence   string
+	}{
+		{payloads.Metadata{ExpectedResponse: `uid=\d+\([\w-]+\)`, Platform: "linux", Risk: "critical"}, "uid=33(www-data) gid=33", 0, true, SeverityCritical, ConfidenceFirm, "[linux] uid=33(www-data)"},
+		{payloads.Metadata{ExpectedResponse: `uid=\d+`}, "nothing here", 0, false, "", "", ""},
+		{payloads.Metadata{ExpectedDelay: 5, Platform: "mysql"}, "ok", 5200 * time.Millisecond, true, SeverityHigh, ConfidenceTentative, "[mysql] response took 5.2s"},
+		{payloads.Metadata{ExpectedDelay: 5}, "ok", 300 * time.Millisecond, false, "", "", ""},
+		{payloads.Metadata{}, "uid=33(www-data)", 10 * time.Second, false, "", "", ""},
+		{payloads.Metadata{ExpectedResponse: `uid=(`}, "uid=33(www-data)", 0, false, "", "", ""},
+		{payloads.Metadata{ExpectedResponse: `uid=(`}, "uid=33(www-data)", 0, false, "", "", ""},
+	}
+	for _, test := range tests {
+		tc := TestCase{
+			InjectionType: "sqli",
+			Metadata:      test.metadata,
+			Response: HTTPResponse{
+				Response:     &http.Response{StatusCode: 200},
+				ResponseText: "HTTP/1.1 200 OK\r\n\r\n" + test.body,
+				Timing:       Timing{Total: test.total},
+			},
+		}
+		finding, evidence, ok := CheckExpectedEvidence(&tc)
+		if ok != test.ok {
+			t.Errorf("Expected %t for %+v got %t\n", test.ok, test.metadata, ok)
+			continue
+		}
+		if ok && (finding.VulnerabilityClass != "SQLI" || finding.Severity != test.severity || finding.Confidence != test.confidence || !strings.HasPrefix(evidence, test.evidence)) {
+			t.Errorf("Expected SQLI %s %s %q for %+v got %+v %q\n", test.severity, test.confidence, test.evidence, test.metadata, finding, evidence)
+		}
+	}
+	first, _ := expectedPattern(`uid=\d+`)
+	if again, _ := expectedPattern(`uid=\d+`); first == nil || again != first {
+		t.Errorf("Expected the pattern to be compiled once\n")
+	}
+}
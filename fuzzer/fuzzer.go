@@ -3,21 +3,19 @@ package fuzzer
 import (
 	"bufio"
 	"bytes"
-	"compress/gzip"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -26,6 +24,7 @@ import (
 	"github.com/gi0cann/pandushi/payloads"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/net/http/httpguts"
 )
 
 // SuccessCodes http success response codes
@@ -115,6 +114,8 @@ type HTTPRequest struct {
 	TotalQueryInjectionPoints  int8   // Total number of Query injection points
 	TotalBodyInjectionPoints   int8   // Total number of Body injection points
 	ForceTLS                   bool   // Force request to use TLS/SSL
+	Raw                        bool   // Send RequestText verbatim with SendRaw instead of net/http
+	RawInjection               bool   // Inject payloads without encoding them for their injection point, the test cases net/http can't send are sent raw
 }
 
 // NewHTTPRequestFromBytes take a []byte and returns a HTTPRequest
@@ -236,12 +237,17 @@ func (req *HTTPRequest) CountInjectionPoints() {
 		}
 		req.Request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 	} else if strings.Contains(ContentType, "multipart/form-data") {
+		// a body shorter than its Content-Length is still parsed up to its closing boundary
+		body, _ := ioutil.ReadAll(req.Request.Body)
+		req.Request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 		err := r.ParseMultipartForm(4096000)
 		if err == nil {
 			req.TotalBodyInjectionPoints += int8(len(r.MultipartForm.File))
 			req.TotalBodyInjectionPoints += int8(len(r.MultipartForm.Value))
 			req.TotalInjectionPoints += req.TotalBodyInjectionPoints
 		}
+		// keep the body to send the request
+		req.Request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 	} else if strings.Contains(ContentType, "application/json") {
 		JSONInterface, err := HTTPRequestToJSONInterface(req)
 		if err != nil {
@@ -287,7 +293,7 @@ func RequestToString(r *http.Request) (string, error) {
 	//query, _ := url.QueryUnescape(r.URL.RawQuery)
 	query := r.URL.RawQuery
 	RequestStr.WriteString(r.Method + " ")
-	RequestStr.WriteString(r.URL.Path + "?" + query + " ")
+	RequestStr.WriteString(r.URL.EscapedPath() + "?" + query + " ")
 	RequestStr.WriteString(r.Proto + "\r\n")
 	RequestStr.WriteString("Host: " + r.Host + "\r\n")
 	body, err := ioutil.ReadAll(r.Body)
@@ -302,6 +308,17 @@ func RequestToString(r *http.Request) (string, error) {
 	return RequestStr.String(), nil
 }
 
+// markPattern matches a mark with its value
+var markPattern = regexp.MustCompile(`§.*?§`)
+
+// unmarkedText removes the § delimiters of the marks of a request text, keeping their values, and updates its Content-Length header
+func unmarkedText(text string) string {
+	text = markPattern.ReplaceAllStringFunc(text, func(mark string) string {
+		return strings.TrimSuffix(strings.TrimPrefix(mark, "§"), "§")
+	})
+	return fixContentLength(text)
+}
+
 // IsMarked check for injection markers inside of a request and return true if found or false if not found.
 func (req *HTTPRequest) IsMarked() bool {
 	Marker := regexp.MustCompile(MarkerRegex)
@@ -311,7 +328,11 @@ func (req *HTTPRequest) IsMarked() bool {
 // HTTPResponse represents a fuzzer HTTP response
 type HTTPResponse struct {
 	Response     *http.Response
-	ResponseText string // String representation of the Response
+	ResponseText string // String representation of the Response, text bodies are converted to UTF-8
+	Body         []byte // Body without its Content-Encoding, before any charset conversion
+	Binary       bool   // The body isn't text, ResponseText only holds its size and hash
+	Truncated    bool   // The body was larger than the maximum body size
+	Timing       Timing // Phases of the request answered by the Response
 }
 
 // NewHTTPResponseFromBytes take a []byte and returns a HTTPResponse
@@ -327,42 +348,77 @@ func NewHTTPResponseFromBytes(resstr []byte, req *http.Request) (res HTTPRespons
 	return res, nil
 }
 
-// ResponseToString takes a http.Response and returns a string
-func ResponseToString(r *http.Response) (string, error) {
+// responseHead returns the status line and headers of a http.Response
+func responseHead(r *http.Response) string {
 	var ResponseStr bytes.Buffer
 	ResponseStr.WriteString(r.Proto + " ")
 	ResponseStr.WriteString(r.Status + "\r\n")
 	for key, header := range r.Header {
 		ResponseStr.WriteString(key + ": " + strings.Join(header, " ") + "\r\n")
 	}
-	var body []byte
-	var reader io.ReadCloser
-	switch r.Header.Get("Content-Encoding") {
-	case "gzip":
-		var err error
-		reader, err = gzip.NewReader(r.Body)
-		if err != nil {
-			return ResponseStr.String(), err
+	return ResponseStr.String()
+}
+
+// readResponseBody reads up to max decoded bytes of the body of a http.Response, removing its Content-Encoding as it is read.
+// Bodies that can't be decoded are returned as sent.
+func readResponseBody(r *http.Response, max int64) ([]byte, bool, error) {
+	encoding := strings.TrimSpace(r.Header.Get("Content-Encoding"))
+	if encoding == "" || strings.EqualFold(encoding, "identity") {
+		return readLimited(r.Body, max)
+	}
+	// the bytes sent are kept until max to return them when they can't be decoded
+	sent := &cappedBuffer{max: max}
+	decoder, closeDecoders, err := decodeReader(io.TeeReader(r.Body, sent), encoding)
+	if err == nil {
+		body, truncated, err := readLimited(decoder, max)
+		closeDecoders()
+		if err == nil {
+			return body, truncated, nil
 		}
-	default:
-		reader = r.Body
 	}
-	body, err := ioutil.ReadAll(reader)
+	return readLimited(io.MultiReader(bytes.NewReader(sent.Bytes()), r.Body), max)
+}
+
+// bodyText returns the text of a response body used in ResponseText
+func bodyText(body []byte, contentType string, truncated bool) (string, bool) {
+	if !IsTextContent(contentType, body) {
+		return binaryText(body), true
+	}
+	text := string(ToUTF8(body, contentType))
+	if truncated {
+		text += fmt.Sprintf(truncationMarker, len(body))
+	}
+	return text, false
+}
+
+// ResponseToString takes a http.Response and returns a string. The body is decoded and converted to UTF-8 when it is text
+func ResponseToString(r *http.Response) (string, error) {
+	head := responseHead(r)
+	body, truncated, err := readResponseBody(r, DefaultMaxBodySize)
 	if err != nil {
-		return ResponseStr.String(), err
+		return head, err
 	}
-	ResponseStr.WriteString("\r\n" + string(body) + "\r\n")
-	return ResponseStr.String(), nil
+	text, _ := bodyText(body, r.Header.Get("Content-Type"), truncated)
+	return head + "\r\n" + text + "\r\n", nil
 }
 
 // NewHTTPResponse takes a http.Response and returns a HTTPResponse
 func NewHTTPResponse(baseres *http.Response) (res HTTPResponse, err error) {
+	return newHTTPResponse(baseres, DefaultMaxBodySize)
+}
+
+// newHTTPResponse is NewHTTPResponse keeping up to max bytes of the body
+func newHTTPResponse(baseres *http.Response, max int64) (res HTTPResponse, err error) {
 	res.Response = baseres
-	resStr, err := ResponseToString(baseres)
+	head := responseHead(baseres)
+	res.Body, res.Truncated, err = readResponseBody(baseres, max)
 	if err != nil {
+		res.ResponseText = head
 		return res, err
 	}
-	res.ResponseText = resStr
+	var text string
+	text, res.Binary = bodyText(res.Body, baseres.Header.Get("Content-Type"), res.Truncated)
+	res.ResponseText = head + "\r\n" + text + "\r\n"
 	return res, nil
 }
 
@@ -377,22 +433,37 @@ type TestCase struct {
 	InjectionPointType string
 	Duration           string
 	Status             string
+	Smuggling          *SmugglingProbe   // Set for SMUGGLING test cases
+	Redirects          []RedirectHop     // Redirects followed before Response
+	Original           string            // Payload before its Pipeline was applied
+	Pipeline           string            // Processors applied to the payload, see payloads.Pipeline
+	Metadata           payloads.Metadata // Verification rule and targets of the payload
+
+	parameter string // Parameter, JSON key or header holding the injection of JSON and marked test cases
 }
 
 // SerializedTestCase is the BSON serialized version of TestCase
 type SerializedTestCase struct {
-	Request            string `bson:"request,omitempty"`
-	Response           string `bson:"response,omitempty"`
-	Injection          string `bson:"injection,omitempty"`
-	InjectionType      string `bson:"injectiontype,omitempty"`
-	InjectionPoint     string `bson:"injectionpoint,omitempty"`
-	InjectionPointType string `bson:"injectionpointtype,omitempty"`
-	Duration           string `bson:"duration,omitempty"`
+	Request            string            `bson:"request,omitempty"`
+	Response           string            `bson:"response,omitempty"`
+	Injection          string            `bson:"injection,omitempty"`
+	InjectionType      string            `bson:"injectiontype,omitempty"`
+	InjectionPoint     string            `bson:"injectionpoint,omitempty"`
+	InjectionPointType string            `bson:"injectionpointtype,omitempty"`
+	Duration           string            `bson:"duration,omitempty"`
+	Redirects          []RedirectHop     `bson:"redirects,omitempty"`
+	Timing             Timing            `bson:"timing"`
+	BodyHash           string            `bson:"bodyhash,omitempty"` // Content address of the body, see SerializedTask.Bodies
+	Binary             bool              `bson:"binary,omitempty"`
+	Truncated          bool              `bson:"truncated,omitempty"`
+	Original           string            `bson:"original,omitempty"`
+	Pipeline           string            `bson:"pipeline,omitempty"`
+	Metadata           payloads.Metadata `bson:"metadata,omitempty"`
 }
 
 // Serialize return a serialize version of TestCase
 func (TC *TestCase) Serialize() SerializedTestCase {
-	return SerializedTestCase{
+	serialized := SerializedTestCase{
 		Request:            TC.Request.RequestText,
 		Response:           TC.Response.ResponseText,
 		Injection:          TC.Injection,
@@ -400,7 +471,18 @@ func (TC *TestCase) Serialize() SerializedTestCase {
 		InjectionPoint:     TC.InjectionPoint,
 		InjectionPointType: TC.InjectionPointType,
 		Duration:           TC.Duration,
+		Redirects:          TC.Redirects,
+		Timing:             TC.Response.Timing,
+		Binary:             TC.Response.Binary,
+		Truncated:          TC.Response.Truncated,
+		Original:           TC.Original,
+		Pipeline:           TC.Pipeline,
+		Metadata:           TC.Metadata,
 	}
+	if TC.Response.Response != nil {
+		serialized.BodyHash = BodyHash(TC.Response.Body)
+	}
+	return serialized
 }
 
 // SupportedInjectionPointTypes is a list of supported injection point types
@@ -413,38 +495,51 @@ var SupportedInjectionPointTypes = []string{
 	"MARKED",
 }
 
-// CreateTestCases takes a arrays of InjectionPointType, InjectionType, and a mongodbURI and returns an array of TestCases
-func CreateTestCases(injectionpointtypes []string, injectiontypes []string, mongodbURI string, request HTTPRequest) ([]TestCase, error) {
+// CreateTestCases takes a arrays of InjectionPointType, InjectionType, and a mongodbURI and returns an array of TestCases.
+// The payloads of generators are added to the stored payloads.
+// The payloads are processed by the Pipeline of their type in pipelines and injected in the injection point types of their Metadata Context.
+func CreateTestCases(injectionpointtypes []string, injectiontypes []string, mongodbURI string, request HTTPRequest, pipelines payloads.Pipelines, generators payloads.Generators) ([]TestCase, error) {
 	var testcases []TestCase
 	payloadArr, err := payloads.CreatePayloadsFromInputTypes(injectiontypes, mongodbURI)
 	if err != nil {
 		return testcases, err
 	}
+	payloadArr = append(payloadArr, generators.Payloads()...)
+	payloadArr = pipelines.Apply(payloadArr)
 
 	for _, injectionpointtype := range injectionpointtypes {
 		injectionpointtype = strings.ToUpper(injectionpointtype)
+		targeted := payloads.ForInjectionPointType(payloadArr, injectionpointtype)
 		if injectionpointtype == "QUERY" {
-			testcases = append(testcases, request.InjectQueryParameters(payloadArr)...)
+			testcases = append(testcases, request.InjectQueryParameters(targeted)...)
 		}
 
 		if injectionpointtype == "JSON" {
-			testcases = append(testcases, request.InjectJSONParameters(payloadArr)...)
+			testcases = append(testcases, request.InjectJSONParameters(targeted)...)
 		}
 
 		if injectionpointtype == "FORM_URLENCODE" {
-			testcases = append(testcases, request.InjectFormURLEncodedBody(payloadArr)...)
+			testcases = append(testcases, request.InjectFormURLEncodedBody(targeted)...)
 		}
 
 		if injectionpointtype == "HEADER" {
-			testcases = append(testcases, request.InjectHeaders(payloadArr)...)
+			testcases = append(testcases, request.InjectHeaders(targeted)...)
 		}
 
 		if injectionpointtype == "PATH" {
-			testcases = append(testcases, request.InjectPath(payloadArr)...)
+			testcases = append(testcases, request.InjectPath(targeted)...)
 		}
 
 		if injectionpointtype == "MARKED" {
-			testcases = append(testcases, request.InjectMarked(payloadArr)...)
+			testcases = append(testcases, request.InjectMarked(targeted)...)
+		}
+
+		if injectionpointtype == "SMUGGLING" {
+			testcases = append(testcases, request.InjectSmuggling()...)
+		}
+
+		if injectionpointtype == "MUTATION" {
+			testcases = append(testcases, request.InjectMutations(nil)...)
 		}
 	}
 
@@ -461,6 +556,12 @@ type Task struct {
 	End            time.Time
 	State          string
 	TestCases      []TestCase
+	Findings       []Finding
+	Progress       *Progress      // Optional progress shared with other Tasks
+	Session        *Session       // Optional session applied to every test case and refreshed when it expires
+	Hooks          []*TokenHook   // Optional token hooks run before every test case is sent
+	Client         *ClientProfile // Optional HTTP client settings, NewClientProfile when nil
+	sharedProgress bool           // Progress belongs to a Batch which counts the TestCases of all its Tasks
 }
 
 // SerializedTask is the bson serialized version of Task
@@ -468,9 +569,12 @@ type SerializedTask struct {
 	Project     string               `bson:"project"`
 	Name        string               `bson:"name"`
 	BaseRequest string               `bson:"baserequest"`
+	ForceTLS    bool                 `bson:"forcetls,omitempty"` // The requests were sent over TLS
 	Start       time.Time            `bson:"start"`
 	End         time.Time            `bson:"end"`
 	TestCases   []SerializedTestCase `bson:"testcases"`
+	Findings    []SerializedFinding  `bson:"findings"`
+	Bodies      []StoredBody         `bson:"bodies,omitempty"` // Response bodies of the TestCases stored once, see ExpandBodies
 }
 
 // Serialize returns a serialized version of Task
@@ -479,19 +583,24 @@ func (T *Task) serialize() SerializedTask {
 		Project:     T.Project,
 		Name:        T.Name,
 		BaseRequest: T.BaseRequest.RequestText,
+		ForceTLS:    T.BaseRequest.ForceTLS,
 		Start:       T.Start,
 		End:         T.End,
 	}
 	for _, tc := range T.TestCases {
 		task.TestCases = append(task.TestCases, tc.Serialize())
 	}
+	for _, f := range T.Findings {
+		task.Findings = append(task.Findings, f.Serialize())
+	}
+	task.dedupBodies(T.TestCases)
 	return task
 }
 
 // NewTask takes a list of InjectionTypes and HTTPRequest and returns a FuzzerTask
-func NewTask(Project string, Name string, InjectionTypes []string, InjectionPointTypes []string, BaseRequest HTTPRequest, mongodbURI string) (Task, error) {
+func NewTask(Project string, Name string, InjectionTypes []string, InjectionPointTypes []string, BaseRequest HTTPRequest, mongodbURI string, Pipelines payloads.Pipelines, Generators payloads.Generators) (Task, error) {
 	var task Task
-	TestCases, err := CreateTestCases(InjectionPointTypes, InjectionTypes, mongodbURI, BaseRequest)
+	TestCases, err := CreateTestCases(InjectionPointTypes, InjectionTypes, mongodbURI, BaseRequest, Pipelines, Generators)
 	if err != nil {
 		return task, err
 	}
@@ -516,56 +625,80 @@ func (T *Task) Run(TotalThreads int, storageconfig StorageConfig, Proxy *url.URL
 	T.Name += "_" + T.Start.Format(time.RFC3339)
 	fmt.Printf("Project Name: %s\n", T.Project)
 	fmt.Printf("Scan Name: %s\n", T.Name)
-	var mutex = &sync.Mutex{}
+	if T.Progress != nil && !T.sharedProgress {
+		T.Progress.Add(len(T.TestCases))
+	}
+	profile := T.Client
+	if profile == nil {
+		profile = NewClientProfile()
+	}
+	httpclient := profile.Client(Proxy)
+	if T.Session != nil {
+		T.Session.Proxy = Proxy
+		T.Session.Profile = profile
+		err := T.Session.DoLogin()
+		if err != nil {
+			fmt.Printf("Task.Run session login error: %s\n", err)
+		}
+	}
+	indexes := make(chan int)
 	var wg sync.WaitGroup
-	for i := range T.TestCases {
-		for j := 0; j < TotalThreads; j++ {
-			wg.Add(1)
-			mutex.Lock()
-			go func(i int) {
-				defer wg.Done()
+	for j := 0; j < TotalThreads; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
 				testcase := &(T.TestCases[i])
-				var transportConfig *http.Transport = &http.Transport{
-                    Proxy: http.ProxyFromEnvironment,
-					DialContext: (&net.Dialer{
-						Timeout:   30 * time.Second,
-						KeepAlive: 30 * time.Second,
-					}).DialContext,
-					ForceAttemptHTTP2:     true,
-					MaxIdleConns:          100,
-					IdleConnTimeout:       90 * time.Second,
-					TLSHandshakeTimeout:   10 * time.Second,
-					ExpectContinueTimeout: 1 * time.Second,
-					TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
+				generation := 0
+				skip := ""
+				if testcase.InjectionPointType == "headers" {
+					// keep the payload of header injections
+					skip = testcase.InjectionPoint
 				}
-                if Proxy != nil {
-                    transportConfig.Proxy = http.ProxyURL(Proxy)
-                }
-                var transport http.RoundTripper = transportConfig
-				httpclient := http.Client{
-					Timeout:   time.Duration(120 * time.Second),
-					Transport: transport,
+				if T.Session != nil {
+					generation = T.Session.Apply(&testcase.Request, skip)
 				}
-
-				testcase.Request.Request.Close = true
-				resp, err := httpclient.Do(testcase.Request.Request)
-				if err != nil {
-					fmt.Printf("Task.Run httpclient error: %s\n", err)
-				} else {
-					httpres, err := NewHTTPResponse(resp)
+				T.applyHooks(testcase, httpclient)
+				testcase.send(httpclient, profile)
+				if T.Session != nil && T.Session.Expired(testcase.Response) {
+					err := T.Session.Refresh(generation)
 					if err != nil {
-						fmt.Printf("Task.Run NewHTTPResponse error: %s\n", err)
+						fmt.Printf("Task.Run session refresh error: %s\n", err)
 					} else {
-						testcase.Response = httpres
+						T.Session.Apply(&testcase.Request, skip)
+						T.applyHooks(testcase, httpclient)
+						testcase.send(httpclient, profile)
 					}
 				}
 				testcase.Status = "Done"
-			}(i)
-			mutex.Unlock()
+				if T.Progress != nil {
+					T.Progress.Increment()
+				}
+			}
+		}()
+	}
+	for i := range T.TestCases {
+		if T.TestCases[i].Smuggling == nil {
+			indexes <- i
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	// smuggling probes poison the front-end connections shared with the other requests so they are sent one at a time
+	for i := range T.TestCases {
+		testcase := &(T.TestCases[i])
+		if testcase.Smuggling == nil {
+			continue
+		}
+		testcase.SendSmuggling(httpclient, profile)
+		testcase.Status = "Done"
+		if T.Progress != nil {
+			T.Progress.Increment()
 		}
-		wg.Wait()
 	}
 	T.End = time.Now()
+	T.Findings = T.Analyze(DefaultChecks)
+	PrintFindingsSummary(T.Findings)
 	serializedTask := T.serialize()
 
 	if storageconfig.UseMongoDB {
@@ -583,22 +716,72 @@ func (T *Task) Run(TotalThreads int, storageconfig StorageConfig, Proxy *url.URL
 	}
 }
 
+// applyHooks runs the token hooks of the Task on testcase without touching its injection point
+func (T *Task) applyHooks(testcase *TestCase, httpclient *http.Client) {
+	skip := testcase.InjectionPoint
+	if testcase.parameter != "" {
+		// the injection points of JSON and marked test cases are mark numbers and offsets
+		skip = testcase.parameter
+	}
+	for _, hook := range T.Hooks {
+		err := hook.Apply(&testcase.Request, httpclient, skip)
+		if err != nil {
+			fmt.Printf("Task.Run token hook error: %s\n", err)
+		}
+	}
+}
+
+// send sends the request of the TestCase with httpclient or SendRawWith and records the response
+func (TC *TestCase) send(httpclient *http.Client, profile *ClientProfile) {
+	if TC.Request.Raw {
+		proxy, err := rawProxy(httpclient, &TC.Request)
+		httpres := HTTPResponse{}
+		if err == nil {
+			httpres, err = TC.Request.SendRawThrough(profile, proxy, httpclient.Timeout)
+		}
+		if err != nil {
+			fmt.Printf("TestCase.send SendRaw error: %s\n", err)
+		} else {
+			TC.Response = httpres
+		}
+	} else {
+		TC.Request.Request.Close = !profile.KeepAlive
+		var chain []RedirectHop
+		request, trace := traceTiming(withRedirectChain(TC.Request.Request, &chain))
+		resp, err := httpclient.Do(request)
+		TC.Redirects = chain
+		if err != nil {
+			fmt.Printf("TestCase.send httpclient error: %s\n", err)
+		} else {
+			httpres, err := newHTTPResponse(resp, profile.MaxBodySize)
+			httpres.Timing = trace.done()
+			if err != nil {
+				fmt.Printf("TestCase.send NewHTTPResponse error: %s\n", err)
+			} else {
+				TC.Response = httpres
+			}
+		}
+	}
+	if TC.Response.Timing.Total > 0 {
+		TC.Duration = TC.Response.Timing.Total.String()
+	}
+}
+
 // InjectQueryParameters take an array of payloads and return an array of TestCases with the payloads injected into query parameters
 func (req *HTTPRequest) InjectQueryParameters(injections []payloads.Payload) []TestCase {
 	var InjectedTestCases []TestCase
 	query := req.Request.URL.Query()
 	for _, injection := range injections {
 		for k := range query {
-			NewQuery := url.Values{}
-			for ik, v := range query {
-				NewQuery.Set(ik, strings.Join(v, ""))
+			if !injection.Injects(k) {
+				continue
 			}
-			NewQuery.Set(k, injection.Value)
-			rawquery := NewQuery.Encode()
+			rawquery := encodeValues(query, k, injection.Value, "query", req.injectsRaw(injection, "query"))
 			NewHTTPRequest, err := NewHTTPRequestFromBytes([]byte(req.RequestText), req.ForceTLS)
 			if err != nil {
 				fmt.Printf("Error Creating HTTPRequest: %s", err)
 			} else {
+				NewHTTPRequest.Raw = req.Raw
 				NewHTTPRequest.Request.URL.RawQuery = rawquery
 				NewRequestText, err := RequestToString(NewHTTPRequest.Request)
 				if err == nil {
@@ -609,6 +792,9 @@ func (req *HTTPRequest) InjectQueryParameters(injections []payloads.Payload) []T
 					Request:            NewHTTPRequest,
 					Injection:          injection.Value,
 					InjectionType:      injection.InputType,
+					Original:           injection.Original,
+					Pipeline:           injection.Pipeline,
+					Metadata:           injection.Metadata,
 					InjectionPoint:     k,
 					InjectionPointType: "query",
 					Status:             "queued",
@@ -642,14 +828,17 @@ func (req *HTTPRequest) InjectHeaders(injections []payloads.Payload) []TestCase
 	for _, injection := range injections {
 		for k := range headers {
 			NewHeaders := headers.Clone()
-			if arrayContains(exclusions, strings.ToLower(k)) {
+			if arrayContains(exclusions, strings.ToLower(k)) || !injection.Injects(k) {
 				continue
 			}
-			NewHeaders.Set(k, injection.Value)
+			value := EncodeInjection("headers", injection.Value, req.injectsRaw(injection, "headers"))
+			NewHeaders.Set(k, value)
 			NewHTTPRequest, err := NewHTTPRequestFromBytes([]byte(req.RequestText), req.ForceTLS)
 			if err != nil {
 				fmt.Printf("Error Creating HTTPRequest: %s", err)
 			} else {
+				// net/http refuses to send header values with control characters, those requests are sent raw
+				NewHTTPRequest.Raw = req.Raw || !httpguts.ValidHeaderFieldValue(value)
 				NewHTTPRequest.Request.Header = NewHeaders
 				NewRequestText, err := RequestToString(NewHTTPRequest.Request)
 				if err == nil {
@@ -660,6 +849,9 @@ func (req *HTTPRequest) InjectHeaders(injections []payloads.Payload) []TestCase
 					Request:            NewHTTPRequest,
 					Injection:          injection.Value,
 					InjectionType:      injection.InputType,
+					Original:           injection.Original,
+					Pipeline:           injection.Pipeline,
+					Metadata:           injection.Metadata,
 					InjectionPoint:     k,
 					InjectionPointType: "headers",
 					Status:             "queued",
@@ -681,16 +873,15 @@ func (req *HTTPRequest) InjectFormURLEncodedBody(injections []payloads.Payload)
 	PostBody := req.Request.PostForm
 	for _, injection := range injections {
 		for k := range PostBody {
-			NewPostBody := url.Values{}
-			for ik, v := range PostBody {
-				NewPostBody.Set(ik, strings.Join(v, ""))
+			if !injection.Injects(k) {
+				continue
 			}
-			NewPostBody.Set(k, injection.Value)
-			rawbody := NewPostBody.Encode()
+			rawbody := encodeValues(PostBody, k, injection.Value, "x-www-form-urlencoded", req.injectsRaw(injection, "x-www-form-urlencoded"))
 			NewHTTPRequest, err := NewHTTPRequestFromBytes([]byte(req.RequestText), req.ForceTLS)
 			if err != nil {
 				fmt.Printf("Error Creating HTTPRequest: %s", err)
 			} else {
+				NewHTTPRequest.Raw = req.Raw
 				NewHTTPRequest.Request.Body = ioutil.NopCloser(bytes.NewBuffer([]byte(rawbody)))
 				NewHTTPRequest.Request.Header.Set("Content-Length", strconv.Itoa(len(rawbody)))
 				NewHTTPRequest.Request.ContentLength = 0
@@ -706,6 +897,9 @@ func (req *HTTPRequest) InjectFormURLEncodedBody(injections []payloads.Payload)
 					Request:            NewHTTPRequest,
 					Injection:          injection.Value,
 					InjectionType:      injection.InputType,
+					Original:           injection.Original,
+					Pipeline:           injection.Pipeline,
+					Metadata:           injection.Metadata,
 					InjectionPoint:     k,
 					InjectionPointType: "x-www-form-urlencoded",
 					Status:             "queued",
@@ -720,21 +914,37 @@ func (req *HTTPRequest) InjectFormURLEncodedBody(injections []payloads.Payload)
 // InjectPath takes an array of payloads and returns an array of TestCases with the payloads injected in the URI path
 func (req *HTTPRequest) InjectPath(injections []payloads.Payload) []TestCase {
 	var InjectedTestCases []TestCase
+	pathList := strings.Split(req.Request.URL.Path, "/")[1:]
+	escapedList := strings.Split(req.Request.URL.EscapedPath(), "/")[1:]
 	for _, injection := range injections {
-		pathList := strings.Split(req.Request.URL.Path, "/")
-		pathList = pathList[1:]
 		for i := range pathList {
-			current := strings.Split(req.Request.URL.Path, "/")
-			current = current[1:]
+			if !injection.Injects(strconv.Itoa(i)) {
+				continue
+			}
+			raw := req.injectsRaw(injection, "path")
+			current := append([]string{}, pathList...)
 			current[i] = injection.Value
+			if unescaped, err := url.PathUnescape(injection.Value); raw && err == nil {
+				current[i] = unescaped
+			}
+			escaped := append([]string{}, escapedList...)
+			escaped[i] = EncodeInjection("path", injection.Value, raw)
 			NewHTTPRequest, err := NewHTTPRequestFromBytes([]byte(req.RequestText), req.ForceTLS)
 			if err != nil {
 				fmt.Printf("Error Creating HTTPRequest: %s\n", err)
 			} else {
 				NewHTTPRequest.Request.URL.Path = "/" + strings.Join(current, "/")
-				NewHTTPRequest.Request.URL.RawPath = "/" + strings.Join(current, "/")
+				NewHTTPRequest.Request.URL.RawPath = "/" + strings.Join(escaped, "/")
+				// net/http re-encodes a RawPath that isn't a valid encoding of Path, those requests are sent raw
+				rewritten := NewHTTPRequest.Request.URL.EscapedPath() != NewHTTPRequest.Request.URL.RawPath
+				NewHTTPRequest.Raw = req.Raw || rewritten
 				NewRequestText, err := RequestToString(NewHTTPRequest.Request)
 				if err == nil {
+					if rewritten {
+						// write the raw path in place of the escaped one
+						requestLine := NewHTTPRequest.Request.Method + " " + NewHTTPRequest.Request.URL.EscapedPath()
+						NewRequestText = NewHTTPRequest.Request.Method + " " + NewHTTPRequest.Request.URL.RawPath + NewRequestText[len(requestLine):]
+					}
 					NewHTTPRequest.RequestText = NewRequestText
 				}
 				InjectedTestCases = append(InjectedTestCases, TestCase{
@@ -742,6 +952,9 @@ func (req *HTTPRequest) InjectPath(injections []payloads.Payload) []TestCase {
 					Request:            NewHTTPRequest,
 					Injection:          injection.Value,
 					InjectionType:      injection.InputType,
+					Original:           injection.Original,
+					Pipeline:           injection.Pipeline,
+					Metadata:           injection.Metadata,
 					InjectionPoint:     pathList[i],
 					InjectionPointType: "path",
 					Status:             "queued",
@@ -776,8 +989,15 @@ func (req *HTTPRequest) InjectJSONParameters(injections []payloads.Payload) []Te
 
 	for _, injection := range injections {
 		for _, v := range marks {
+			if !injection.Injects(v) {
+				continue
+			}
 			pattern := regexp.MustCompile(`§` + v + `.*?§`)
-			injected := pattern.ReplaceAll(jsonBytes, []byte(injection.Value))
+			var parameter string
+			if loc := pattern.FindIndex(jsonBytes); loc != nil {
+				parameter = markedParameter(string(jsonBytes), loc[0])
+			}
+			injected := pattern.ReplaceAllLiteral(jsonBytes, []byte(EncodeInjection("json", injection.Value, req.injectsRaw(injection, "json"))))
 			for _, vi := range marks {
 				pattern := regexp.MustCompile(`§` + vi + `.*?§`)
 				pattern2 := regexp.MustCompile(`§` + vi + `(.*?)§`)
@@ -786,12 +1006,13 @@ func (req *HTTPRequest) InjectJSONParameters(injections []payloads.Payload) []Te
 					continue
 				}
 				replacer := submatch[1]
-				injected = pattern.ReplaceAll(injected, replacer)
+				injected = pattern.ReplaceAllLiteral(injected, replacer)
 			}
 			NewHTTPRequest, err := NewHTTPRequestFromBytes([]byte(req.RequestText), req.ForceTLS)
 			if err != nil {
 				fmt.Printf("Error Creating HTTPRequest: %s", err)
 			} else {
+				NewHTTPRequest.Raw = req.Raw
 				NewHTTPRequest.Request.Body = ioutil.NopCloser(bytes.NewBuffer([]byte(injected)))
 				NewHTTPRequest.Request.Header.Set("Content-Length", strconv.Itoa(len(injected)))
 				NewHTTPRequest.Request.ContentLength = 0
@@ -804,9 +1025,13 @@ func (req *HTTPRequest) InjectJSONParameters(injections []payloads.Payload) []Te
 					Request:            NewHTTPRequest,
 					Injection:          injection.Value,
 					InjectionType:      injection.InputType,
+					Original:           injection.Original,
+					Pipeline:           injection.Pipeline,
+					Metadata:           injection.Metadata,
 					InjectionPoint:     "",
 					InjectionPointType: "json",
 					Status:             "queued",
+					parameter:          parameter,
 				})
 			}
 		}
@@ -815,7 +1040,22 @@ func (req *HTTPRequest) InjectJSONParameters(injections []payloads.Payload) []Te
 	return InjectedTestCases
 }
 
-// InjectMarked takes a array of payloads and returns a array of TestCases with the payloads injected in the JSON body of each HTTP request
+// jsonValueMarks reports which marks of a request text with a JSON body are JSON values of the body instead of parts of a string
+func jsonValueMarks(text string, indexes [][]int) []bool {
+	bare := make([]bool, len(indexes))
+	if !strings.Contains(headerFromRequestText(text, "Content-Type"), "json") {
+		return bare
+	}
+	_, _, rest := splitRequestText(text)
+	bodyStart := len(text) - len(rest)
+	for i, index := range indexes {
+		bare[i] = index[0] > bodyStart && text[index[0]-1] != '"' && (index[1] == len(text) || text[index[1]] != '"')
+	}
+	return bare
+}
+
+// InjectMarked takes a array of payloads and returns a array of TestCases with the payloads injected in the marked positions of the HTTP request.
+// The marks that aren't injected keep their values and the Content-Length header is updated to the injected body.
 func (req *HTTPRequest) InjectMarked(injections []payloads.Payload) []TestCase {
 	var InjectedTestCases []TestCase
 
@@ -829,22 +1069,33 @@ func (req *HTTPRequest) InjectMarked(injections []payloads.Payload) []TestCase {
 			current = index[1]
 		}
 		reqArr[len(reqArr)-1] = string(req.RequestText[current:])
+		// inject value at the mark i, the other marks keep their values
+		inject := func(i int, value string) string {
+			newReqArr := make([]string, len(reqArr))
+			copy(newReqArr, reqArr)
+			newReqArr[i] = pattern.ReplaceAllLiteralString(newReqArr[i], value)
+			return unmarkedText(strings.Join(newReqArr, ""))
+		}
+		bare := jsonValueMarks(req.RequestText, indexes)
 		for _, injection := range injections {
 			for i := range indexes {
-				newReqArr := make([]string, len(reqArr))
-				copy(newReqArr, reqArr)
-				newReqArr[i] = pattern.ReplaceAllString(newReqArr[i], injection.Value)
-				newReqString := strings.Join(newReqArr, "")
-				newReqString = pattern.ReplaceAllString(newReqString, "")
+				if !injection.Injects(strconv.Itoa(i)) {
+					continue
+				}
+				value := injection.Value
+				if bare[i] && !req.RawInjection && !json.Valid([]byte(value)) {
+					// numbers and booleans of a JSON body are marked unquoted, string payloads are quoted to keep the body valid
+					value = `"` + jsonEscape(value) + `"`
+				}
+				newReqString := inject(i, value)
 				NewHTTPRequest, err := NewHTTPRequestFromBytes([]byte(newReqString), req.ForceTLS)
-
+				NewHTTPRequest.Raw = req.Raw
+				if err != nil && (req.Raw || req.RawInjection) {
+					// requests net/http can't parse are sent raw
+					NewHTTPRequest, err = HTTPRequest{RequestText: newReqString, ForceTLS: req.ForceTLS, Raw: true}, nil
+				}
 				if err != nil {
-					newReqArr = make([]string, len(reqArr))
-					copy(newReqArr, reqArr)
-					newReqArr[i] = pattern.ReplaceAllString(newReqArr[i], url.QueryEscape(injection.Value))
-					newReqString = strings.Join(newReqArr, "")
-					newReqString = pattern.ReplaceAllString(newReqString, "")
-					NewHTTPRequest, err = NewHTTPRequestFromBytes([]byte(newReqString), req.ForceTLS)
+					NewHTTPRequest, err = NewHTTPRequestFromBytes([]byte(inject(i, url.QueryEscape(injection.Value))), req.ForceTLS)
 				}
 				if err != nil {
 					fmt.Printf("Error Creating HTTPRequest: %s", err)
@@ -854,9 +1105,13 @@ func (req *HTTPRequest) InjectMarked(injections []payloads.Payload) []TestCase {
 						Request:            NewHTTPRequest,
 						Injection:          injection.Value,
 						InjectionType:      injection.InputType,
+						Original:           injection.Original,
+						Pipeline:           injection.Pipeline,
+						Metadata:           injection.Metadata,
 						InjectionPoint:     strconv.Itoa(indexes[i][0]) + " - " + strconv.Itoa(indexes[i][1]),
 						InjectionPointType: "marked",
 						Status:             "queued",
+						parameter:          markedParameter(req.RequestText, indexes[i][0]),
 					})
 				}
 			}
@@ -881,7 +1136,7 @@ func markjson(data interface{}, count *int, marks *[]string, marker string) inte
 			if typeOfValue == reflect.Map || typeOfValue == reflect.Slice {
 				returnSlice[i] = markjson(v, count, marks, marker)
 			} else {
-				returnSlice[i] = marker + strconv.Itoa(*count) + reflect.ValueOf(v).String() + marker
+				returnSlice[i] = marker + strconv.Itoa(*count) + fmt.Sprint(v) + marker
 				*marks = append(*marks, strconv.Itoa(*count))
 				*count++
 			}
@@ -890,12 +1145,15 @@ func markjson(data interface{}, count *int, marks *[]string, marker string) inte
 	} else if reflect.ValueOf(data).Kind() == reflect.Map {
 		d := reflect.ValueOf(data)
 		tmpData := make(map[string]interface{})
-		for _, k := range d.MapKeys() {
+		// sorted keys number the marks of the same JSON the same way every time
+		keys := d.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
 			typeOfValue := reflect.TypeOf(d.MapIndex(k).Interface()).Kind()
 			if typeOfValue == reflect.Map || typeOfValue == reflect.Slice {
 				tmpData[k.String()] = markjson(d.MapIndex(k).Interface(), count, marks, marker)
 			} else {
-				tmpData[k.String()] = marker + strconv.Itoa(*count) + reflect.ValueOf(d.MapIndex(k).Interface()).String() + marker
+				tmpData[k.String()] = marker + strconv.Itoa(*count) + fmt.Sprint(d.MapIndex(k).Interface()) + marker
 				*marks = append(*marks, strconv.Itoa(*count))
 				*count++
 			}
@@ -906,14 +1164,13 @@ func markjson(data interface{}, count *int, marks *[]string, marker string) inte
 	return data
 }
 
-// CheckTarget takes a request object and a list of errorcodes returns false if response to the request matches the error code and true if it doesn't
-func CheckTarget(req *HTTPRequest, successcodes []int) error {
+// CheckTarget takes a request object and a list of errorcodes returns false if response to the request matches the error code and true if it doesn't. The request is sent with profile, NewClientProfile when nil
+func CheckTarget(req *HTTPRequest, successcodes []int, profile *ClientProfile) error {
 	var checkReq HTTPRequest
 	var err error
 	if req.IsMarked() {
-		pattern := regexp.MustCompile(`§.*?§`)
-		newReqStr := pattern.ReplaceAllString(req.RequestText, "")
-		checkReq, err = NewHTTPRequestFromBytes([]byte(newReqStr), req.ForceTLS)
+		// the marks hold the values of the request, such as the example values of imported specifications
+		checkReq, err = NewHTTPRequestFromBytes([]byte(unmarkedText(req.RequestText)), req.ForceTLS)
 		if err != nil {
 			return err
 		}
@@ -922,25 +1179,26 @@ func CheckTarget(req *HTTPRequest, successcodes []int) error {
 	}
 
 	allowed := false
-	var transport http.RoundTripper = &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
-		ForceAttemptHTTP2:     true,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-		TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
-	}
-	httpclient := http.Client{
-		Timeout:   time.Duration(120 * time.Second),
-		Transport: transport,
-	}
-
-	resp, err := httpclient.Do(checkReq.Request)
+	if profile == nil {
+		profile = NewClientProfile()
+	}
+	httpclient := profile.Client(nil)
+
+	var resp *http.Response
+	if req.Raw {
+		var res HTTPResponse
+		var proxy *url.URL
+		proxy, err = rawProxy(httpclient, &checkReq)
+		if err == nil {
+			res, err = checkReq.SendRawThrough(profile, proxy, httpclient.Timeout)
+		}
+		if err == nil && res.Response == nil {
+			err = errors.New("malformed response")
+		}
+		resp = res.Response
+	} else {
+		resp, err = httpclient.Do(checkReq.Request)
+	}
 	if err != nil {
 		fmt.Println(err)
 		return err
@@ -960,7 +1218,7 @@ func CheckTarget(req *HTTPRequest, successcodes []int) error {
 
 // ResultsToFile will write the results of a fuzzing Task to a file
 func ResultsToFile(projectName string, task SerializedTask) error {
-	fd, err := os.OpenFile(projectName+".json", os.O_RDWR|os.O_CREATE, 0755)
+	fd, err := os.OpenFile(projectName+".json", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
 	if err != nil {
 		log.Printf("ResultsToFile error: %s\n", err)
 		return err
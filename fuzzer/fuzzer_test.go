@@ -93,7 +93,7 @@ Accept-Encoding: gzip, deflate
 Accept-Language: en-US,en;q=0.9
 Connection: close
 Content-Type: application/x-www-form-urlencoded
-Content-Length: 19
+Content-Length: 14
 
 foo=bar&hello=
 `,
@@ -106,7 +106,7 @@ Accept-Encoding: gzip, deflate
 Accept-Language: en-US,en;q=0.9
 Connection: close
 Content-Type: application/x-www-form-urlencoded
-Content-Length: 19
+Content-Length: 39
 
 foo=bar&hello=<script>alert(1)</script>
 `,
@@ -119,7 +119,7 @@ Accept-Encoding: gzip, deflate
 Accept-Language: en-US,en;q=0.9
 Connection: close
 Content-Type: application/x-www-form-urlencoded
-Content-Length: 19
+Content-Length: 14
 
 foo=bar&hello=
 `,
@@ -132,7 +132,7 @@ Accept-Encoding: gzip, deflate
 Accept-Language: en-US,en;q=0.9
 Connection: close
 Content-Type: application/x-www-form-urlencoded
-Content-Length: 19
+Content-Length: 24
 
 foo=bar&hello=' or 1=1--
 `,
@@ -0,0 +1,104 @@
+package fuzzer
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+// InjectionPointValues returns the original values of the injection points of an injection point type of req by injection point.
+// Path segments and marked positions are numbered from 0, JSON values by their mark.
+func (req *HTTPRequest) InjectionPointValues(injectionPointType string) map[string]string {
+	values := make(map[string]string)
+	if req.Request == nil {
+		return values
+	}
+	switch strings.ToUpper(injectionPointType) {
+	case "QUERY":
+		for k := range req.Request.URL.Query() {
+			values[k] = req.Request.URL.Query().Get(k)
+		}
+	case "HEADER":
+		for k := range req.Request.Header {
+			values[k] = req.Request.Header.Get(k)
+		}
+	case "FORM_URLENCODE":
+		for k := range req.Request.PostForm {
+			values[k] = req.Request.PostForm.Get(k)
+		}
+	case "PATH":
+		for i, segment := range strings.Split(req.Request.URL.Path, "/")[1:] {
+			values[strconv.Itoa(i)] = segment
+		}
+	case "JSON":
+		ContentType := req.Request.Header.Get("Content-Type")
+		if !(strings.Contains(ContentType, "application/json") || strings.Contains(ContentType, "application/text")) {
+			return values
+		}
+		JSONInterface, err := HTTPRequestToJSONInterface(req)
+		m, ok := JSONInterface.(map[string]interface{})
+		if err != nil || !ok {
+			return values
+		}
+		var marks []string
+		count := 0
+		jsonBytes, err := json.Marshal(markjson(m, &count, &marks, `§`))
+		if err != nil {
+			return values
+		}
+		for _, v := range marks {
+			submatch := regexp.MustCompile(`§` + v + `(.*?)§`).FindSubmatch(jsonBytes)
+			if len(submatch) != 2 {
+				continue
+			}
+			// the marks are inside JSON strings, unquote them to get the values
+			var value string
+			if json.Unmarshal([]byte(`"`+string(submatch[1])+`"`), &value) == nil {
+				values[v] = value
+			}
+		}
+	case "MARKED":
+		pattern := regexp.MustCompile(`§(.*?)§`)
+		for i, submatch := range pattern.FindAllStringSubmatch(req.RequestText, -1) {
+			values[strconv.Itoa(i)] = submatch[1]
+		}
+	}
+	return values
+}
+
+// InjectMutations returns MUTATION TestCases injecting the mutations of the original value of every injection point of req in that injection point.
+// Marked requests are only mutated at their marks, mutators are payloads.DefaultMutators when empty.
+func (req *HTTPRequest) InjectMutations(mutators []payloads.Mutator) []TestCase {
+	var InjectedTestCases []TestCase
+	injectionPointTypes := []string{"QUERY", "JSON", "FORM_URLENCODE", "HEADER", "PATH"}
+	if req.IsMarked() {
+		injectionPointTypes = []string{"MARKED"}
+	}
+	for _, injectionPointType := range injectionPointTypes {
+		var mutations []payloads.Payload
+		for point, original := range req.InjectionPointValues(injectionPointType) {
+			mutations = append(mutations, payloads.Mutate(point, original, mutators)...)
+		}
+		if len(mutations) == 0 {
+			continue
+		}
+		switch injectionPointType {
+		case "QUERY":
+			InjectedTestCases = append(InjectedTestCases, req.InjectQueryParameters(mutations)...)
+		case "JSON":
+			InjectedTestCases = append(InjectedTestCases, req.InjectJSONParameters(mutations)...)
+		case "FORM_URLENCODE":
+			InjectedTestCases = append(InjectedTestCases, req.InjectFormURLEncodedBody(mutations)...)
+		case "HEADER":
+			InjectedTestCases = append(InjectedTestCases, req.InjectHeaders(mutations)...)
+		case "PATH":
+			InjectedTestCases = append(InjectedTestCases, req.InjectPath(mutations)...)
+		case "MARKED":
+			InjectedTestCases = append(InjectedTestCases, req.InjectMarked(mutations)...)
+		}
+	}
+	return InjectedTestCases
+}
@@ -0,0 +1,68 @@
+package fuzzer
+
+import (
+	"testing"
+
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+func TestInjectionPointValues(t *testing.T) {
+	tests := []struct {
+		request            string
+		injectionPointType string
+		expected           map[string]string
+	}{
+		{"GET /users/42?page=2&q=a+b HTTP/1.1\r\nHost: example.com\r\n\r\n", "QUERY", map[string]string{"page": "2", "q": "a b"}},
+		{"GET /users/42 HTTP/1.1\r\nHost: example.com\r\n\r\n", "PATH", map[string]string{"0": "users", "1": "42"}},
+		{"GET / HTTP/1.1\r\nHost: example.com\r\nX-Id: 7\r\n\r\n", "HEADER", map[string]string{"X-Id": "7"}},
+		{"POST / HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 12\r\n\r\nuser=a&pin=1", "FORM_URLENCODE", map[string]string{"user": "a", "pin": "1"}},
+		{"POST / HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/json\r\nContent-Length: 35\r\n\r\n{\"b\":\"x\\\"y\",\"a\":{\"c\":5,\"d\":true}}", "JSON", map[string]string{"0": "5", "1": "true", "2": `x"y`}},
+		{"GET /?id=§42§&name=§bob§ HTTP/1.1\r\nHost: example.com\r\n\r\n", "MARKED", map[string]string{"0": "42", "1": "bob"}},
+	}
+	for _, test := range tests {
+		req, err := NewHTTPRequestFromBytes([]byte(test.request), false)
+		if err != nil {
+			t.Fatalf("Error creating HTTPRequest: %s\n", err)
+		}
+		values := req.InjectionPointValues(test.injectionPointType)
+		for point, value := range test.expected {
+			if values[point] != value {
+				t.Errorf("Expected %s %s to be %q got %q\n", test.injectionPointType, point, value, values[point])
+			}
+		}
+		if test.injectionPointType != "HEADER" && len(values) != len(test.expected) {
+			t.Errorf("Expected %d %s values got %v\n", len(test.expected), test.injectionPointType, values)
+		}
+	}
+}
+
+func TestInjectMutations(t *testing.T) {
+	req, err := NewHTTPRequestFromBytes([]byte("GET /items/7?page=2&sort=name HTTP/1.1\r\nHost: example.com\r\n\r\n"), false)
+	if err != nil {
+		t.Fatalf("Error creating HTTPRequest: %s\n", err)
+	}
+	double := payloads.Mutator{Name: "double", Mutate: func(original string) []string { return []string{original + original} }}
+	testcases := req.InjectMutations([]payloads.Mutator{double})
+	expected := map[string]string{"page": "22", "sort": "namename", "items": "itemsitems", "7": "77"}
+	if len(testcases) != len(expected) {
+		t.Fatalf("Expected one test case per injection point got %d\n", len(testcases))
+	}
+	for _, tc := range testcases {
+		if expected[tc.InjectionPoint] != tc.Injection || tc.InjectionType != payloads.MutationType {
+			t.Errorf("Expected the mutation of %s to be %s got %s %s\n", tc.InjectionPoint, expected[tc.InjectionPoint], tc.InjectionType, tc.Injection)
+		}
+	}
+
+	marked, err := NewHTTPRequestFromBytes([]byte("GET /?id=§42§&name=§bob§ HTTP/1.1\r\nHost: example.com\r\n\r\n"), false)
+	if err != nil {
+		t.Fatalf("Error creating HTTPRequest: %s\n", err)
+	}
+	testcases = marked.InjectMutations([]payloads.Mutator{double})
+	queries := make(map[string]bool)
+	for _, tc := range testcases {
+		queries[tc.Request.Request.URL.RawQuery] = true
+	}
+	if len(testcases) != 2 || !queries["id=4242&name=bob"] || !queries["id=42&name=bobbob"] {
+		t.Errorf("Expected each mark to be mutated from its own value got %v\n", queries)
+	}
+}
@@ -0,0 +1,84 @@
+package fuzzer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Progress tracks the number of test cases sent across one or more Tasks
+type Progress struct {
+	total int64
+	done  int64
+	start time.Time
+	out   io.Writer
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewProgress returns a Progress that prints to stderr
+func NewProgress() *Progress {
+	return &Progress{out: os.Stderr}
+}
+
+// Add adds n test cases to the total
+func (P *Progress) Add(n int) {
+	atomic.AddInt64(&P.total, int64(n))
+}
+
+// Increment marks a test case as done
+func (P *Progress) Increment() {
+	atomic.AddInt64(&P.done, 1)
+}
+
+// String returns a one line summary of the progress
+func (P *Progress) String() string {
+	done := atomic.LoadInt64(&P.done)
+	total := atomic.LoadInt64(&P.total)
+	percent := 0.0
+	if total > 0 {
+		percent = float64(done) * 100 / float64(total)
+	}
+	elapsed := time.Since(P.start)
+	rate := 0.0
+	eta := "-"
+	if elapsed > 0 && done > 0 {
+		rate = float64(done) / elapsed.Seconds()
+		eta = (time.Duration(float64(total-done)/rate) * time.Second).Round(time.Second).String()
+	}
+	return fmt.Sprintf("[%d/%d] %.1f%% %.1f req/s elapsed %s eta %s", done, total, percent, rate, elapsed.Round(time.Second), eta)
+}
+
+// Start prints the progress every interval until Stop is called
+func (P *Progress) Start(interval time.Duration) {
+	P.start = time.Now()
+	P.stop = make(chan struct{})
+	P.wg.Add(1)
+	go func() {
+		defer P.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fmt.Fprintf(P.out, "\r%s", P)
+			case <-P.stop:
+				fmt.Fprintf(P.out, "\r%s\n", P)
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops printing the progress after printing it one last time
+func (P *Progress) Stop() {
+	if P.stop == nil {
+		return
+	}
+	close(P.stop)
+	P.wg.Wait()
+	P.stop = nil
+}
@@ -0,0 +1,210 @@
+package fuzzer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RawTimeout is the default timeout of a raw request
+const RawTimeout = 120 * time.Second
+
+// ErrNoHost is returned when the target of a raw request can't be found
+var ErrNoHost = errors.New("raw request has no Host header")
+
+// RawTarget returns the host:port a raw request is sent to.
+// The host of the parsed request is used when available, otherwise the Host header is read from RequestText.
+func (req *HTTPRequest) RawTarget() (string, error) {
+	host := ""
+	if req.Request != nil {
+		host = req.Request.URL.Host
+	}
+	if host == "" {
+		for _, line := range strings.Split(req.RequestText, "\n") {
+			line = strings.TrimRight(line, "\r")
+			if line == "" {
+				break
+			}
+			if len(line) > 5 && strings.EqualFold(line[:5], "host:") {
+				host = strings.TrimSpace(line[5:])
+				break
+			}
+		}
+	}
+	if host == "" {
+		return "", ErrNoHost
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		port := "80"
+		if req.ForceTLS {
+			port = "443"
+		}
+		host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+	}
+	return host, nil
+}
+
+// SendRaw writes RequestText verbatim over TCP or TLS and parses the raw response.
+// When the response can't be parsed ResponseText holds the raw bytes read and Response is nil.
+func (req *HTTPRequest) SendRaw(timeout time.Duration) (HTTPResponse, error) {
+	return req.SendRawWith(nil, timeout)
+}
+
+// SendRawWith is SendRaw opening the connection with the dialers and TLS settings of profile
+func (req *HTTPRequest) SendRawWith(profile *ClientProfile, timeout time.Duration) (HTTPResponse, error) {
+	return req.SendRawThrough(profile, nil, timeout)
+}
+
+// rawProxy returns the proxy the transport of httpclient uses for the target of a raw request, nil for a direct connection
+func rawProxy(httpclient *http.Client, req *HTTPRequest) (*url.URL, error) {
+	transport, ok := httpclient.Transport.(*http.Transport)
+	if !ok || transport.Proxy == nil {
+		return nil, nil
+	}
+	target, err := req.RawTarget()
+	if err != nil {
+		return nil, err
+	}
+	scheme := "http"
+	if req.ForceTLS {
+		scheme = "https"
+	}
+	return transport.Proxy(&http.Request{Method: http.MethodGet, URL: &url.URL{Scheme: scheme, Host: target}, Header: make(http.Header)})
+}
+
+// dialProxy opens a tunnel to target through the http or https proxy with a CONNECT request
+func (P *ClientProfile) dialProxy(ctx context.Context, proxy *url.URL, target string) (net.Conn, error) {
+	port := proxy.Port()
+	switch {
+	case proxy.Scheme == "http" && port == "":
+		port = "80"
+	case proxy.Scheme == "https" && port == "":
+		port = "443"
+	case proxy.Scheme != "http" && proxy.Scheme != "https":
+		return nil, fmt.Errorf("raw requests can't be sent through the %s proxy %s, only http and https proxies are supported", proxy.Scheme, proxy.Host)
+	}
+	address := net.JoinHostPort(proxy.Hostname(), port)
+	conn, err := P.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if proxy.Scheme == "https" {
+		conn, err = P.handshake(conn, address)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	connect := &http.Request{Method: http.MethodConnect, URL: &url.URL{Opaque: target}, Host: target, Header: make(http.Header)}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		connect.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(proxy.User.Username()+":"+password)))
+	}
+	err = connect.Write(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), connect)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused to connect to %s: %s", proxy.Host, target, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// SendRawThrough is SendRawWith tunneling the connection through proxy with CONNECT, the connection is direct when proxy is nil
+func (req *HTTPRequest) SendRawThrough(profile *ClientProfile, proxy *url.URL, timeout time.Duration) (HTTPResponse, error) {
+	var res HTTPResponse
+	target, err := req.RawTarget()
+	if err != nil {
+		return res, err
+	}
+	if timeout == 0 {
+		timeout = RawTimeout
+	}
+	if profile == nil {
+		profile = NewClientProfile()
+	}
+	var timing Timing
+	start := time.Now()
+	var conn net.Conn
+	if proxy != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		conn, err = profile.dialProxy(ctx, proxy, target)
+		cancel()
+	} else {
+		conn, err = profile.DialContext(context.Background(), "tcp", target)
+	}
+	if err != nil {
+		return res, err
+	}
+	timing.Connect = time.Since(start)
+	if req.ForceTLS {
+		handshakeStart := time.Now()
+		conn, err = profile.handshake(conn, target)
+		if err != nil {
+			return res, err
+		}
+		timing.TLS = time.Since(handshakeStart)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	_, err = io.WriteString(conn, req.RequestText)
+	if err != nil {
+		return res, err
+	}
+
+	var raw bytes.Buffer
+	reader := bufio.NewReader(io.TeeReader(conn, &raw))
+	var firstByte time.Time
+	if _, err := reader.Peek(1); err == nil {
+		firstByte = time.Now()
+	}
+	resp, err := http.ReadResponse(reader, req.Request)
+	if err != nil {
+		// keep whatever the server sent for malformed responses
+		readLimited(reader, profile.MaxBodySize)
+		res.ResponseText = raw.String()
+		res.Timing = timing.finish(start, firstByte)
+		if res.ResponseText != "" {
+			return res, nil
+		}
+		return res, err
+	}
+	limit := profile.MaxBodySize
+	if limit > 0 {
+		// one more byte lets newHTTPResponse mark the body as truncated
+		limit++
+	}
+	body, _, err := readLimited(resp.Body, limit)
+	resp.Body.Close()
+	if err != nil && len(body) == 0 {
+		res.ResponseText = raw.String()
+		res.Timing = timing.finish(start, firstByte)
+		return res, nil
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	res, err = newHTTPResponse(resp, profile.MaxBodySize)
+	res.Timing = timing.finish(start, firstByte)
+	return res, err
+}
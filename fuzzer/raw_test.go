@@ -0,0 +1,176 @@
+package fuzzer
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+// rawServer accepts a single connection, records the request head and writes response
+func rawServer(t *testing.T, response string) (string, chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %s\n", err)
+	}
+	received := make(chan string, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- ""
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		reader := bufio.NewReader(conn)
+		var head strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			head.WriteString(line)
+			if err != nil || line == "\r\n" || line == "\n" {
+				break
+			}
+		}
+		received <- head.String()
+		conn.Write([]byte(response))
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestSendRaw(t *testing.T) {
+	tests := []struct {
+		request  string
+		response string
+		status   int
+		body     string
+	}{
+		{
+			"GET /?a=1 HTTP/1.1\r\nHost: %s\r\nx-lower-CASE: one\r\nX-Injected: a\r\nSet-Cookie: b\r\n\r\n",
+			"HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello",
+			200,
+			"hello",
+		},
+		{
+			"GET / HTTP/1.1\r\nHost: %s\r\nBad Header Line\r\n\r\n",
+			"HTTP/1.1 400 Bad Request\r\nContent-Length: 3\r\nConnection: close\r\n\r\nbad",
+			400,
+			"bad",
+		},
+		{
+			"GET / HTTP/1.1\r\nHost: %s\r\n\r\n",
+			"garbage response\r\n",
+			0,
+			"garbage response\r\n",
+		},
+	}
+	for _, test := range tests {
+		addr, received := rawServer(t, test.response)
+		text := strings.Replace(test.request, "%s", addr, 1)
+		req := HTTPRequest{RequestText: text, Raw: true}
+		res, err := req.SendRaw(5 * time.Second)
+		if err != nil {
+			t.Fatalf("Error sending raw request: %s\n", err)
+		}
+		if got := <-received; got != text {
+			t.Errorf("Expected the request to be sent verbatim %q got %q\n", text, got)
+		}
+		if test.status == 0 {
+			if res.Response != nil || res.ResponseText != test.body {
+				t.Errorf("Expected the raw malformed response %q got %q\n", test.body, res.ResponseText)
+			}
+			continue
+		}
+		if res.Response == nil || res.Response.StatusCode != test.status {
+			t.Errorf("Expected status %d got %v\n", test.status, res.Response)
+		}
+		if !strings.HasSuffix(res.ResponseText, "\r\n"+test.body+"\r\n") {
+			t.Errorf("Expected body %q in %q\n", test.body, res.ResponseText)
+		}
+	}
+}
+
+func TestSendRawThroughProxy(t *testing.T) {
+	target, received := rawServer(t, "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello")
+	connects := make(chan string, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		connects <- r.Host
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, buffered, _ := w.(http.Hijacker).Hijack()
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go func() {
+			io.Copy(upstream, buffered)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	defer proxy.Close()
+
+	request := "GET / HTTP/1.1\r\nHost: " + target + "\r\nx-lower-CASE: one\r\nBad Header Line\r\n\r\n"
+	req := HTTPRequest{RequestText: request, Raw: true}
+	profile := NewClientProfile()
+	proxyURL, _ := url.Parse(strings.Replace(proxy.URL, "http://", "http://user:pass@", 1))
+	tc := TestCase{Request: req}
+	tc.send(profile.Client(proxyURL), profile)
+	if host := <-connects; host != target {
+		t.Errorf("Expected a CONNECT to %s got %s\n", target, host)
+	}
+	if head := <-received; head != request {
+		t.Errorf("Expected the request to be tunneled verbatim got %q\n", head)
+	}
+	if tc.Response.Response == nil || tc.Response.Response.StatusCode != 200 || string(tc.Response.Body) != "hello" {
+		t.Errorf("Expected the response of the target got %q\n", tc.Response.ResponseText)
+	}
+
+	proxyURL, _ = url.Parse(proxy.URL)
+	if _, err := req.SendRawThrough(profile, proxyURL, time.Second); err == nil || !strings.Contains(err.Error(), "407") {
+		t.Errorf("Expected the refused CONNECT to fail got %v\n", err)
+	}
+	proxyURL, _ = url.Parse("socks5://127.0.0.1:1080")
+	if _, err := req.SendRawThrough(profile, proxyURL, time.Second); err == nil {
+		t.Errorf("Expected raw requests through a socks5 proxy to fail\n")
+	}
+}
+
+func TestInjectMarkedRaw(t *testing.T) {
+	base := HTTPRequest{
+		RequestText: "GET /§a§ HTTP/1.1\r\nHost: example.com\r\nX-Test: §b§\r\n\r\n",
+		Raw:         true,
+	}
+	testcases := base.InjectMarked([]payloads.Payload{payloads.New("CRLF", "x\r\nInjected: 1")})
+	if len(testcases) != 2 {
+		t.Fatalf("Expected 2 test cases got %d\n", len(testcases))
+	}
+	expected := []string{
+		"GET /x\r\nInjected: 1 HTTP/1.1\r\nHost: example.com\r\nX-Test: b\r\n\r\n",
+		"GET /a HTTP/1.1\r\nHost: example.com\r\nX-Test: x\r\nInjected: 1\r\n\r\n",
+	}
+	for i, tc := range testcases {
+		if !tc.Request.Raw {
+			t.Errorf("Expected test case %d to be sent raw\n", i)
+		}
+		if tc.Request.RequestText != expected[i] {
+			t.Errorf("Expected verbatim request %q got %q\n", expected[i], tc.Request.RequestText)
+		}
+	}
+	target, err := testcases[0].Request.RawTarget()
+	if err != nil || target != "example.com:80" {
+		t.Errorf("Expected target example.com:80 got %s %v\n", target, err)
+	}
+}
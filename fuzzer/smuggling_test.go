@@ -0,0 +1,192 @@
+package fuzzer
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// readHead reads the request line and headers of a raw request
+func readHead(reader *bufio.Reader) ([]string, error) {
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return lines, err
+		}
+		if line == "\r\n" || line == "\n" {
+			return lines, nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+// serveTEBackend is a back-end stand-in that prefers Transfer-Encoding over Content-Length
+func serveTEBackend(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for {
+				lines, err := readHead(reader)
+				if err != nil || len(lines) == 0 {
+					return
+				}
+				chunked := false
+				length := 0
+				for _, line := range lines[1:] {
+					i := strings.Index(line, ":")
+					if i < 0 {
+						continue
+					}
+					name, value := line[:i], strings.TrimSpace(line[i+1:])
+					if strings.EqualFold(name, "Transfer-Encoding") && strings.EqualFold(value, "chunked") {
+						chunked = true
+					}
+					if strings.EqualFold(name, "Content-Length") {
+						length, _ = strconv.Atoi(value)
+					}
+				}
+				if chunked {
+					for {
+						line, err := reader.ReadString('\n')
+						if err != nil {
+							return
+						}
+						size, err := strconv.ParseInt(strings.TrimSpace(line), 16, 64)
+						if err != nil {
+							conn.Write([]byte("HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\nConnection: close\r\n\r\n"))
+							return
+						}
+						if size == 0 {
+							reader.ReadString('\n')
+							break
+						}
+						if _, err := io.CopyN(ioutil.Discard, reader, size+2); err != nil {
+							return
+						}
+					}
+				} else if _, err := io.CopyN(ioutil.Discard, reader, int64(length)); err != nil {
+					return
+				}
+				method := strings.Fields(lines[0])[0]
+				if method != http.MethodGet && method != http.MethodPost {
+					conn.Write([]byte("HTTP/1.1 405 Method Not Allowed\r\nContent-Length: 0\r\n\r\n"))
+				} else {
+					conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok"))
+				}
+			}
+		}(conn)
+	}
+}
+
+// serveCLFrontend is a front-end stand-in that only uses Content-Length and reuses a single back-end connection
+func serveCLFrontend(listener net.Listener, backend string, timeout time.Duration) {
+	var mutex sync.Mutex
+	var upstream net.Conn
+	var upstreamReader *bufio.Reader
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			lines, err := readHead(reader)
+			if err != nil {
+				return
+			}
+			length := 0
+			for _, line := range lines[1:] {
+				if i := strings.Index(line, ":"); i >= 0 && strings.EqualFold(strings.TrimSpace(line[:i]), "Content-Length") {
+					length, _ = strconv.Atoi(strings.TrimSpace(line[i+1:]))
+				}
+			}
+			body := make([]byte, length)
+			if _, err := io.ReadFull(reader, body); err != nil {
+				return
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if upstream == nil {
+				upstream, err = net.Dial("tcp", backend)
+				if err != nil {
+					return
+				}
+				upstreamReader = bufio.NewReader(upstream)
+			}
+			upstream.Write([]byte(strings.Join(lines, "") + "\r\n" + string(body)))
+			upstream.SetReadDeadline(time.Now().Add(timeout))
+			resp, err := http.ReadResponse(upstreamReader, nil)
+			if err != nil {
+				upstream.Close()
+				upstream = nil
+				conn.Write([]byte("HTTP/1.1 504 Gateway Timeout\r\nContent-Length: 0\r\n\r\n"))
+				return
+			}
+			resp.Write(conn)
+		}(conn)
+	}
+}
+
+func TestSmugglingProbes(t *testing.T) {
+	timeout, delay := SmugglingTimeout, SmugglingDelay
+	SmugglingTimeout, SmugglingDelay = 2*time.Second, 200*time.Millisecond
+	defer func() { SmugglingTimeout, SmugglingDelay = timeout, delay }()
+
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %s\n", err)
+	}
+	defer backend.Close()
+	go serveTEBackend(backend)
+	frontend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %s\n", err)
+	}
+	defer frontend.Close()
+	go serveCLFrontend(frontend, backend.Addr().String(), 300*time.Millisecond)
+
+	base, err := NewHTTPRequestFromBytes([]byte("GET /?a=1 HTTP/1.1\r\nHost: "+frontend.Addr().String()+"\r\nUser-Agent: pandushi\r\n\r\n"), false)
+	if err != nil {
+		t.Fatalf("Error creating HTTPRequest: %s\n", err)
+	}
+	testcases := base.InjectSmuggling()
+	for _, tc := range testcases {
+		if !tc.Request.Raw || tc.InjectionPointType != "smuggling" || tc.Smuggling == nil {
+			t.Fatalf("Expected raw smuggling test cases got %+v\n", tc)
+		}
+	}
+	task := Task{Name: "smuggling", BaseRequest: base, TestCases: testcases}
+	task.Run(4, StorageConfig{}, nil)
+
+	points := make(map[string]bool)
+	for _, f := range task.Findings {
+		if f.VulnerabilityClass == "HTTP_REQUEST_SMUGGLING" {
+			points[f.InjectionPoint] = true
+		}
+	}
+	for _, expected := range []string{"CL.TE timing", "CL.TE differential", "TE.TE CL.TE timing"} {
+		if !points[expected] {
+			t.Errorf("Expected a smuggling finding for %s got %v\n", expected, points)
+		}
+	}
+	for point := range points {
+		if strings.Contains(point, SmugglingTECL) {
+			t.Errorf("Unexpected smuggling finding for %s against a CL.TE stand-in\n", point)
+		}
+	}
+}
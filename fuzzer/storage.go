@@ -0,0 +1,91 @@
+package fuzzer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrTaskNotFound is returned when a stored Task can't be found
+var ErrTaskNotFound = errors.New("task not found")
+
+// runSuffix is the pattern of the start time Task.Run appends to the name of a Task
+const runSuffix = `_\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(Z|[+-]\d{2}:\d{2})`
+
+// taskNamePattern matches name alone or followed by the start time appended by Task.Run
+func taskNamePattern(name string) string {
+	return "^" + regexp.QuoteMeta(name) + "(" + runSuffix + ")?$"
+}
+
+// LoadTaskFromFile reads a SerializedTask written by ResultsToFile.
+// When the file was written by BatchResultsToFile the first task named name, or name followed by its start time, is returned.
+func LoadTaskFromFile(filename string, name string) (SerializedTask, error) {
+	var task SerializedTask
+	var batch SerializedBatch
+	if !strings.HasSuffix(filename, ".json") {
+		filename += ".json"
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return task, err
+	}
+	err = json.Unmarshal(data, &batch)
+	if err == nil && batch.Tasks != nil {
+		pattern := regexp.MustCompile(taskNamePattern(name))
+		for _, t := range batch.Tasks {
+			if pattern.MatchString(t.Name) {
+				t.ExpandBodies()
+				return t, nil
+			}
+		}
+		return task, ErrTaskNotFound
+	}
+	err = json.Unmarshal(data, &task)
+	task.ExpandBodies()
+	return task, err
+}
+
+// LoadTaskFromMongoDB returns the most recent SerializedTask of project named name, or name followed by its start time
+func LoadTaskFromMongoDB(mongodbURI string, project string, name string) (SerializedTask, error) {
+	var task SerializedTask
+	mclient, err := mongo.NewClient(options.Client().ApplyURI(mongodbURI))
+	if err != nil {
+		return task, err
+	}
+	ctx := context.Background()
+	err = mclient.Connect(ctx)
+	if err != nil {
+		return task, err
+	}
+	defer mclient.Disconnect(ctx)
+	taskCollection := mclient.Database("pandushi").Collection("tasks")
+	filter := bson.M{
+		"project": project,
+		"name":    bson.M{"$regex": taskNamePattern(name)},
+	}
+	opts := options.FindOne().SetSort(bson.M{"start": -1})
+	err = taskCollection.FindOne(ctx, filter, opts).Decode(&task)
+	if err == mongo.ErrNoDocuments {
+		return task, ErrTaskNotFound
+	}
+	task.ExpandBodies()
+	return task, err
+}
+
+// LoadTaskFromURI loads a SerializedTask from a file:// or mongodb:// storage URI
+func LoadTaskFromURI(URI string, project string, name string) (SerializedTask, error) {
+	if strings.HasPrefix(URI, "file://") {
+		return LoadTaskFromFile(strings.Replace(URI, "file://", "", 1), name)
+	}
+	if strings.HasPrefix(URI, "mongodb://") {
+		return LoadTaskFromMongoDB(URI, project, name)
+	}
+	return SerializedTask{}, errors.New("unsupported storage URI: " + URI)
+}
@@ -0,0 +1,97 @@
+package fuzzer
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing holds the phases of sending a request. Phases repeated by redirects are added together, DNS is part of Connect for raw requests
+type Timing struct {
+	DNS       time.Duration `bson:"dns"`       // Host name resolution
+	Connect   time.Duration `bson:"connect"`   // TCP connection
+	TLS       time.Duration `bson:"tls"`       // TLS handshake
+	FirstByte time.Duration `bson:"firstbyte"` // From the start of the request to the first byte of the response
+	Transfer  time.Duration `bson:"transfer"`  // Reading the response body
+	Total     time.Duration `bson:"total"`
+	Reused    bool          `bson:"reused"` // The connection was reused from a previous request
+}
+
+// timingTrace records a Timing with net/http/httptrace
+type timingTrace struct {
+	mutex        sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	firstByte    time.Time
+	timing       Timing
+}
+
+// traceTiming returns a copy of req recording its Timing into the returned timingTrace
+func traceTiming(req *http.Request) (*http.Request, *timingTrace) {
+	t := &timingTrace{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mutex.Lock()
+			t.dnsStart = time.Now()
+			t.mutex.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mutex.Lock()
+			t.timing.DNS += time.Since(t.dnsStart)
+			t.mutex.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			t.mutex.Lock()
+			t.connectStart = time.Now()
+			t.mutex.Unlock()
+		},
+		ConnectDone: func(string, string, error) {
+			t.mutex.Lock()
+			t.timing.Connect += time.Since(t.connectStart)
+			t.mutex.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mutex.Lock()
+			t.tlsStart = time.Now()
+			t.mutex.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mutex.Lock()
+			t.timing.TLS += time.Since(t.tlsStart)
+			t.mutex.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mutex.Lock()
+			t.timing.Reused = info.Reused
+			t.mutex.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mutex.Lock()
+			t.firstByte = time.Now()
+			t.mutex.Unlock()
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), t
+}
+
+// finish returns the Timing with the first byte, transfer and total phases of a request started at start
+func (T Timing) finish(start time.Time, firstByte time.Time) Timing {
+	end := time.Now()
+	if !firstByte.IsZero() {
+		T.FirstByte = firstByte.Sub(start)
+		T.Transfer = end.Sub(firstByte)
+	}
+	T.Total = end.Sub(start)
+	return T
+}
+
+// done returns the Timing once the response body has been read
+func (t *timingTrace) done() Timing {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.timing.finish(t.start, t.firstByte)
+}
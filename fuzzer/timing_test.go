@@ -0,0 +1,47 @@
+package fuzzer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+func TestTestCaseTiming(t *testing.T) {
+	// the transfer starts when the client sees the first byte, which a busy client sees late,
+	// so only the first byte and the whole response are bounded by the server sleeps
+	const sleep = 50 * time.Millisecond
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(sleep)
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		time.Sleep(sleep)
+		w.Write([]byte("last"))
+	}))
+	defer server.Close()
+
+	for _, raw := range []bool{false, true} {
+		base, err := NewHTTPRequestFromBytes([]byte("GET /?q=1 HTTP/1.1\r\nHost: "+strings.TrimPrefix(server.URL, "https://")+"\r\nConnection: close\r\n\r\n"), true)
+		if err != nil {
+			t.Fatalf("Error creating HTTPRequest: %s\n", err)
+		}
+		base.Raw = raw
+		task := Task{Name: "timing", BaseRequest: base, TestCases: base.InjectQueryParameters([]payloads.Payload{payloads.New("XSS", "x")})}
+		task.Run(1, StorageConfig{}, nil)
+
+		tc := task.TestCases[0]
+		timing := tc.Serialize().Timing
+		if timing.Connect <= 0 || timing.TLS <= 0 {
+			t.Errorf("Expected connect and TLS timings with raw %v got %+v\n", raw, timing)
+		}
+		if timing.FirstByte < sleep || timing.Transfer <= 0 || timing.FirstByte+timing.Transfer < 2*sleep || timing.Total < timing.FirstByte+timing.Transfer {
+			t.Errorf("Expected a first byte timing of at least %s and first byte and transfer timings of at least %s with raw %v got %+v\n", sleep, 2*sleep, raw, timing)
+		}
+		if tc.Duration != timing.Total.String() {
+			t.Errorf("Expected Duration %s got %s\n", timing.Total, tc.Duration)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package fuzzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// jsonPathPattern matches a key and its optional [index] suffixes in a JSON path segment
+var jsonPathPattern = regexp.MustCompile(`^([^\[\]]*)((?:\[\d+\])*)$`)
+
+// jsonIndexPattern matches the indexes of the [index] suffixes of a JSON path segment
+var jsonIndexPattern = regexp.MustCompile(`\d+`)
+
+// jsonKeyPattern matches the JSON key before a value at the end of a line
+var jsonKeyPattern = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"\s*:\s*"?$`)
+
+// parameterPattern matches the query or form parameter before a value at the end of a line
+var parameterPattern = regexp.MustCompile(`(?:^|[?&])([^?&=\s]+)=$`)
+
+// headerPattern matches the header name before a value at the end of a line
+var headerPattern = regexp.MustCompile(`^([^:\s]+):[ \t]*$`)
+
+// markedParameter returns the JSON key, query or form parameter or header whose value starts at start in text
+func markedParameter(text string, start int) string {
+	line := text[strings.LastIndex(text[:start], "\n")+1 : start]
+	if match := jsonKeyPattern.FindStringSubmatch(line); match != nil {
+		var key string
+		if json.Unmarshal([]byte(`"`+match[1]+`"`), &key) == nil {
+			return key
+		}
+		return match[1]
+	}
+	if match := parameterPattern.FindStringSubmatch(line); match != nil {
+		return match[1]
+	}
+	if match := headerPattern.FindStringSubmatch(line); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// selectHTML returns the attribute or the text of the first element of a HTML document matching selector
+func selectHTML(text string, selector cascadia.Selector, attribute string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(text))
+	if err != nil {
+		return "", err
+	}
+	node := selector.MatchFirst(doc)
+	if node == nil {
+		return "", errors.New("no element matches the CSS selector")
+	}
+	if attribute != "" {
+		for _, attr := range node.Attr {
+			if strings.EqualFold(attr.Key, attribute) {
+				return attr.Val, nil
+			}
+		}
+		return "", fmt.Errorf("element has no %s attribute", attribute)
+	}
+	var content strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			content.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+	return strings.TrimSpace(content.String()), nil
+}
+
+// selectJSON returns the value at path of a JSON document. Paths look like $.data.items[0].token
+func selectJSON(text string, path string) (string, error) {
+	var value interface{}
+	err := json.Unmarshal([]byte(text), &value)
+	if err != nil {
+		return "", err
+	}
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path != "" {
+		for _, segment := range strings.Split(path, ".") {
+			match := jsonPathPattern.FindStringSubmatch(segment)
+			if match == nil {
+				return "", fmt.Errorf("invalid JSON path segment %s", segment)
+			}
+			if match[1] != "" {
+				object, ok := value.(map[string]interface{})
+				if !ok {
+					return "", fmt.Errorf("%s is not an object", segment)
+				}
+				if value, ok = object[match[1]]; !ok {
+					return "", fmt.Errorf("missing JSON key %s", match[1])
+				}
+			}
+			for _, index := range jsonIndexPattern.FindAllString(match[2], -1) {
+				array, ok := value.([]interface{})
+				i, _ := strconv.Atoi(index)
+				if !ok || i >= len(array) {
+					return "", fmt.Errorf("missing JSON index %s[%d]", match[1], i)
+				}
+				value = array[i]
+			}
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		data, err := json.Marshal(v)
+		return string(data), err
+	}
+}
+
+// rewindBody makes the body of r readable again after it was sent
+func rewindBody(r *http.Request) {
+	if r.Body == nil {
+		return
+	}
+	if r.GetBody == nil {
+		body, _ := ioutil.ReadAll(r.Body)
+		r.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	r.Body, _ = r.GetBody()
+}
+
+// TokenHook fetches a page before a test case is sent and substitutes a fresh token, such as an anti-CSRF token, into it
+type TokenHook struct {
+	Request     string    `json:"request"`      // Raw HTTP request of the page holding the token
+	RequestFile string    `json:"request_file"` // File containing the raw HTTP request, used when Request is empty
+	ForceTLS    bool      `json:"force_tls"`
+	Extract     Extractor `json:"extract"`
+	Parameter   string    `json:"parameter"` // Query, form or JSON parameter receiving the token
+	Header      string    `json:"header"`    // Header receiving the token
+
+	placeholder   *regexp.Regexp
+	parameter     *regexp.Regexp
+	jsonParameter *regexp.Regexp
+}
+
+// compile compiles the Extractor of the TokenHook and the patterns substituting its token
+func (H *TokenHook) compile() error {
+	err := H.Extract.compile()
+	if err != nil {
+		return err
+	}
+	H.placeholder = regexp.MustCompile(`{{\s*` + regexp.QuoteMeta(H.Extract.Name) + `\s*}}`)
+	if H.Parameter != "" {
+		H.parameter = regexp.MustCompile(`((?:^|&)` + regexp.QuoteMeta(H.Parameter) + `=)[^&]*`)
+		H.jsonParameter = regexp.MustCompile(`("` + regexp.QuoteMeta(H.Parameter) + `"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	}
+	return nil
+}
+
+// NewTokenHooksFromFile reads a JSON list of TokenHooks
+func NewTokenHooksFromFile(filename string) ([]*TokenHook, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var hooks []*TokenHook
+	err = json.Unmarshal(data, &hooks)
+	if err != nil {
+		return nil, err
+	}
+	for i, hook := range hooks {
+		if hook.Request == "" && hook.RequestFile != "" {
+			text, err := ioutil.ReadFile(hook.RequestFile)
+			if err != nil {
+				return nil, err
+			}
+			hook.Request = string(text)
+		}
+		if hook.Request == "" {
+			return nil, fmt.Errorf("token hook %d has no request", i)
+		}
+		err = hook.compile()
+		if err != nil {
+			return nil, fmt.Errorf("token hook %d: %s", i, err)
+		}
+	}
+	return hooks, nil
+}
+
+// replaceParameter replaces the value of the parameter matched by pattern in a query string or x-www-form-urlencoded body
+func replaceParameter(text string, pattern *regexp.Regexp, value string) string {
+	return pattern.ReplaceAllString(text, "${1}"+strings.Replace(value, "$", "$$", -1))
+}
+
+// replaceJSONParameter replaces the string value of the key matched by pattern in a JSON body
+func replaceJSONParameter(text string, pattern *regexp.Regexp, value string) string {
+	quoted, _ := json.Marshal(value)
+	return pattern.ReplaceAllString(text, "${1}"+strings.Replace(string(quoted), "$", "$$", -1))
+}
+
+// mergeCookies sets cookies into the value of a Cookie header
+func mergeCookies(current string, cookies []*http.Cookie) string {
+	var pairs []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(current, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name := strings.SplitN(part, "=", 2)[0]
+		for _, cookie := range cookies {
+			if cookie.Name == name {
+				part = name + "=" + cookie.Value
+				seen[name] = true
+			}
+		}
+		pairs = append(pairs, part)
+	}
+	for _, cookie := range cookies {
+		if !seen[cookie.Name] {
+			pairs = append(pairs, cookie.Name+"="+cookie.Value)
+			seen[cookie.Name] = true
+		}
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// substitute writes token and the cookies set with it into a raw request, skip is an injection point left untouched
+func (H *TokenHook) substitute(text string, token string, cookies []*http.Cookie, skip string) string {
+	text = H.placeholder.ReplaceAllLiteralString(text, token)
+
+	if H.Parameter != "" && H.Parameter != skip {
+		lines, eol, rest := splitRequestText(text)
+		parts := strings.SplitN(lines[0], " ", 3)
+		if len(parts) == 3 {
+			if i := strings.Index(parts[1], "?"); i >= 0 {
+				parts[1] = parts[1][:i+1] + replaceParameter(parts[1][i+1:], H.parameter, token)
+				lines[0] = strings.Join(parts, " ")
+			}
+		}
+		separator := eol + eol
+		if strings.HasPrefix(rest, separator) {
+			body := rest[len(separator):]
+			body = replaceParameter(body, H.parameter, token)
+			body = replaceJSONParameter(body, H.jsonParameter, token)
+			rest = separator + body
+		}
+		text = strings.Join(lines, eol) + rest
+	}
+	if H.Header != "" && !strings.EqualFold(H.Header, skip) {
+		text = setRequestTextHeader(text, H.Header, token)
+	}
+	if len(cookies) > 0 {
+		text = setRequestTextHeader(text, "Cookie", mergeCookies(headerFromRequestText(text, "Cookie"), cookies))
+	}
+	return fixContentLength(text)
+}
+
+// Apply fetches a fresh token with httpclient and substitutes it into req, skip is an injection point left untouched
+func (H *TokenHook) Apply(req *HTTPRequest, httpclient *http.Client, skip string) error {
+	text := H.Request
+	if cookie := headerFromRequestText(req.RequestText, "Cookie"); cookie != "" {
+		// tokens are usually bound to the session of the test case
+		text = setRequestTextHeader(text, "Cookie", cookie)
+	}
+	page, err := NewHTTPRequestFromBytes([]byte(text), H.ForceTLS)
+	if err != nil {
+		return err
+	}
+	resp, err := httpclient.Do(page.Request)
+	if err != nil {
+		return err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	token, err := H.Extract.Extract(resp, string(body))
+	if err != nil {
+		return err
+	}
+
+	req.RequestText = H.substitute(req.RequestText, token, resp.Cookies(), skip)
+	if req.Raw || req.Request == nil {
+		return nil
+	}
+	parsed, err := NewHTTPRequestFromBytes([]byte(req.RequestText), req.ForceTLS)
+	if err != nil {
+		return err
+	}
+	req.Request = parsed.Request
+	rewindBody(req.Request)
+	return nil
+}
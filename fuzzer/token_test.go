@@ -0,0 +1,176 @@
+package fuzzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+func TestExtractorSelectors(t *testing.T) {
+	page := `<html><body><form><input type="hidden" name="csrf" value="abc123"><span class="t"> text token </span></form></body></html>`
+	document := `{"data":{"items":[{"token":"x1"},{"token":"x2"}],"count":2}}`
+	tests := []struct {
+		extractor Extractor
+		body      string
+		expected  string
+	}{
+		{Extractor{Name: "a", CSS: "input[name=csrf]", Attribute: "value"}, page, "abc123"},
+		{Extractor{Name: "a", CSS: "span.t"}, page, "text token"},
+		{Extractor{Name: "a", CSS: "span.t", Regex: `(\w+)$`}, page, "token"},
+		{Extractor{Name: "a", JSONPath: "$.data.items[1].token"}, document, "x2"},
+		{Extractor{Name: "a", JSONPath: "data.count"}, document, "2"},
+		{Extractor{Name: "a", Regex: `"token":"([^"]+)"`}, document, "x1"},
+	}
+	for _, test := range tests {
+		err := test.extractor.compile()
+		if err != nil {
+			t.Fatalf("Error compiling extractor: %s\n", err)
+		}
+		got, err := test.extractor.Extract(&http.Response{Header: http.Header{}}, test.body)
+		if err != nil || got != test.expected {
+			t.Errorf("Expected %q got %q (%v)\n", test.expected, got, err)
+		}
+	}
+}
+
+func TestTokenHookSubstitute(t *testing.T) {
+	tests := []struct {
+		hook     TokenHook
+		text     string
+		skip     string
+		expected string
+	}{
+		{TokenHook{Extract: Extractor{Name: "csrf"}, Parameter: "csrf"}, "GET /a?csrf=old&q=1 HTTP/1.1\r\nHost: a\r\n\r\n", "", "GET /a?csrf=new&q=1 HTTP/1.1\r\nHost: a\r\n\r\n"},
+		{TokenHook{Extract: Extractor{Name: "csrf"}, Parameter: "csrf"}, "POST /a HTTP/1.1\r\nHost: a\r\nContent-Length: 14\r\n\r\nq=1&csrf=old12", "", "POST /a HTTP/1.1\r\nHost: a\r\nContent-Length: 12\r\n\r\nq=1&csrf=new"},
+		{TokenHook{Extract: Extractor{Name: "csrf"}, Parameter: "csrf"}, "POST /a HTTP/1.1\r\nHost: a\r\nContent-Length: 15\r\n\r\n{\"csrf\": \"old\"}", "", "POST /a HTTP/1.1\r\nHost: a\r\nContent-Length: 15\r\n\r\n{\"csrf\": \"new\"}"},
+		{TokenHook{Extract: Extractor{Name: "csrf"}, Parameter: "csrf"}, "GET /a?csrf=payload HTTP/1.1\r\nHost: a\r\n\r\n", "csrf", "GET /a?csrf=payload HTTP/1.1\r\nHost: a\r\n\r\n"},
+		{TokenHook{Extract: Extractor{Name: "csrf"}, Header: "X-CSRF"}, "GET /{{csrf}} HTTP/1.1\r\nHost: a\r\n\r\n", "", "GET /new HTTP/1.1\r\nHost: a\r\nX-CSRF: new\r\n\r\n"},
+	}
+	for _, test := range tests {
+		if err := test.hook.compile(); err != nil {
+			t.Fatalf("Error compiling token hook: %s\n", err)
+		}
+		if got := test.hook.substitute(test.text, "new", nil, test.skip); got != test.expected {
+			t.Errorf("Expected %q got %q\n", test.expected, got)
+		}
+	}
+}
+
+func TestTokenHookCSRF(t *testing.T) {
+	var mutex sync.Mutex
+	tokens := make(map[string]string)
+	issued := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if r.URL.Path == "/form" {
+			// every form is bound to a fresh cookie and token
+			issued++
+			sid, token := fmt.Sprintf("s%d", issued), fmt.Sprintf("t%d", issued)
+			tokens[sid] = token
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: sid})
+			fmt.Fprintf(w, `<form><input name="csrf" value="%s"></form>`, token)
+			return
+		}
+		r.ParseForm()
+		cookie, err := r.Cookie("sid")
+		if err != nil || tokens[cookie.Value] == "" || tokens[cookie.Value] != r.PostForm.Get("csrf") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		// tokens are single use
+		delete(tokens, cookie.Value)
+		w.Write([]byte("saved " + r.PostForm.Get("name")))
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	hook := &TokenHook{
+		Request:   "GET /form HTTP/1.1\r\nHost: " + host + "\r\n\r\n",
+		Extract:   Extractor{Name: "csrf", CSS: "input[name=csrf]", Attribute: "value"},
+		Parameter: "csrf",
+	}
+	err := hook.compile()
+	if err != nil {
+		t.Fatalf("Error compiling token hook: %s\n", err)
+	}
+	base, err := NewHTTPRequestFromBytes([]byte("POST /save HTTP/1.1\r\nHost: "+host+"\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 19\r\n\r\nname=bob&csrf=stale"), false)
+	if err != nil {
+		t.Fatalf("Error creating HTTPRequest: %s\n", err)
+	}
+	var injections []payloads.Payload
+	for i := 0; i < 4; i++ {
+		injections = append(injections, payloads.New("XSS", fmt.Sprintf("p%d", i)))
+	}
+	task := Task{Name: "hooks", BaseRequest: base, TestCases: base.InjectFormURLEncodedBody(injections), Hooks: []*TokenHook{hook}}
+	task.Run(2, StorageConfig{}, nil)
+
+	for _, tc := range task.TestCases {
+		res := tc.Response.Response
+		if tc.InjectionPoint == "csrf" {
+			if res == nil || res.StatusCode != http.StatusForbidden {
+				t.Errorf("Expected the payload of the csrf injection point to be kept got %q\n", tc.Request.RequestText)
+			}
+			continue
+		}
+		if res == nil || res.StatusCode != http.StatusOK || !strings.HasSuffix(tc.Response.ResponseText, "saved "+tc.Injection+"\r\n") {
+			t.Errorf("Expected a fresh token for %s got %q\n", tc.Injection, tc.Response.ResponseText)
+		}
+	}
+}
+
+func TestTokenHookJSONInjectionPoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Write([]byte(`{"token":"fresh"}`))
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	hook := &TokenHook{
+		Request:   "GET /token HTTP/1.1\r\nHost: " + host + "\r\n\r\n",
+		Extract:   Extractor{Name: "csrf", JSONPath: "token"},
+		Parameter: "csrf",
+	}
+	err := hook.compile()
+	if err != nil {
+		t.Fatalf("Error compiling token hook: %s\n", err)
+	}
+	injections := []payloads.Payload{payloads.New("XSS", "<payload>")}
+	var testcases []TestCase
+	for _, body := range []string{`{"csrf":"stale","name":"bob"}`, `{"csrf":"§stale§","name":"§bob§"}`} {
+		base, err := NewHTTPRequestFromBytes([]byte(fmt.Sprintf("POST /save HTTP/1.1\r\nHost: %s\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s", host, len(body), body)), false)
+		if err != nil {
+			t.Fatalf("Error creating HTTPRequest: %s\n", err)
+		}
+		if base.IsMarked() {
+			testcases = append(testcases, base.InjectMarked(injections)...)
+		} else {
+			testcases = append(testcases, base.InjectJSONParameters(injections)...)
+		}
+	}
+	task := Task{Name: "hooks", TestCases: testcases, Hooks: []*TokenHook{hook}}
+	task.Run(1, StorageConfig{}, nil)
+
+	if len(task.TestCases) != 4 {
+		t.Fatalf("Expected 4 test cases got %d\n", len(task.TestCases))
+	}
+	for _, tc := range task.TestCases {
+		text := tc.Request.RequestText
+		body := text[strings.Index(text, "\r\n\r\n")+4:]
+		var fields map[string]string
+		if err := json.Unmarshal([]byte(body), &fields); err != nil {
+			t.Fatalf("Error parsing the sent body %s: %s\n", body, err)
+		}
+		if fields["csrf"] != "<payload>" && (fields["name"] != "<payload>" || fields["csrf"] != "fresh") {
+			t.Errorf("Expected the payload in the fuzzed field and a fresh token otherwise got %s\n", body)
+		}
+	}
+}
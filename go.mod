@@ -4,5 +4,10 @@ go 1.14
 
 require (
 	github.com/akamensky/argparse v1.2.2
+	github.com/andybalholm/brotli v1.0.1
+	github.com/andybalholm/cascadia v1.1.0
+	github.com/klauspost/compress v1.11.3
 	go.mongodb.org/mongo-driver v1.4.1
+	golang.org/x/net v0.0.0-20200202094626-16171245cfb2
+	gopkg.in/yaml.v2 v2.3.0
 )
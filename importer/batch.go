@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gi0cann/pandushi/fuzzer"
+)
+
+// RequestRecord is a line of a JSONL request list.
+// A record contains either a raw HTTP request or the parts of a request.
+type RequestRecord struct {
+	Request  string            `json:"request"`
+	ForceTLS bool              `json:"force_tls"`
+	Method   string            `json:"method"`
+	URL      string            `json:"url"`
+	Headers  map[string]string `json:"headers"`
+	Body     string            `json:"body"`
+}
+
+// HTTPRequest converts the record to a HTTPRequest
+func (r *RequestRecord) HTTPRequest(forceTLS bool) (fuzzer.HTTPRequest, error) {
+	if r.Request != "" {
+		return fuzzer.NewHTTPRequestFromBytes([]byte(r.Request), forceTLS || r.ForceTLS)
+	}
+	headers := http.Header{}
+	for k, v := range r.Headers {
+		headers.Set(k, v)
+	}
+	method := r.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body []byte
+	if r.Body != "" {
+		body = []byte(r.Body)
+	}
+	return NewHTTPRequest(method, r.URL, headers, body)
+}
+
+// ParseJSONL takes the content of a JSONL request list and returns a HTTPRequest for each record
+func ParseJSONL(data []byte, forceTLS bool) ([]fuzzer.HTTPRequest, error) {
+	var requests []fuzzer.HTTPRequest
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var record RequestRecord
+		err := json.Unmarshal([]byte(text), &record)
+		if err != nil {
+			return requests, fmt.Errorf("line %d: %s", line, err)
+		}
+		request, err := record.HTTPRequest(forceTLS)
+		if err != nil {
+			return requests, fmt.Errorf("line %d: %s", line, err)
+		}
+		requests = append(requests, request)
+	}
+	return requests, scanner.Err()
+}
+
+// NewHTTPRequestsFromDirectory reads every .req file of a directory in name order
+func NewHTTPRequestsFromDirectory(dirname string, forceTLS bool) ([]fuzzer.HTTPRequest, error) {
+	var requests []fuzzer.HTTPRequest
+	filenames, err := filepath.Glob(filepath.Join(dirname, "*.req"))
+	if err != nil {
+		return requests, err
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		text, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return requests, err
+		}
+		request, err := fuzzer.NewHTTPRequestFromBytes(text, forceTLS)
+		if err != nil {
+			return requests, fmt.Errorf("%s: %s", filename, err)
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+// NewHTTPRequestsFromList loads requests from either a directory of .req files or a JSONL request list
+func NewHTTPRequestsFromList(name string, forceTLS bool) ([]fuzzer.HTTPRequest, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return NewHTTPRequestsFromDirectory(name, forceTLS)
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJSONL(data, forceTLS)
+}
@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"testing"
+)
+
+func TestParseJSONL(t *testing.T) {
+	input := `{"request": "GET /test.php?foo=bar HTTP/1.1\r\nHost: localhost:8009\r\n\r\n"}
+
+{"request": "GET /secure HTTP/1.1\nHost: localhost:8443\n\n", "force_tls": true}
+{"method": "POST", "url": "https://localhost:8443/api", "headers": {"Content-Type": "application/json"}, "body": "{\"a\": \"b\"}"}
+`
+	requests, err := ParseJSONL([]byte(input), false)
+	if err != nil {
+		t.Fatalf("ParseJSONL error: %s\n", err)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 requests got %d\n", len(requests))
+	}
+	expectedURLs := []string{
+		"http://localhost:8009/test.php?foo=bar",
+		"https://localhost:8443/secure?",
+		"https://localhost:8443/api",
+	}
+	for i, expected := range expectedURLs {
+		if requests[i].Request.URL.String() != expected {
+			t.Errorf("Request %d expected URL: %s got: %s\n", i, expected, requests[i].Request.URL)
+		}
+	}
+	if requests[2].TotalBodyInjectionPoints != 1 {
+		t.Errorf("Expected 1 body injection point got %d\n", requests[2].TotalBodyInjectionPoints)
+	}
+
+	_, err = ParseJSONL([]byte("{\"request\": \"GET / HTTP/1.1\\r\\nHost: a\\r\\n\\r\\n\"}\nnot json\n"), false)
+	if err == nil || err.Error()[:6] != "line 2" {
+		t.Errorf("Expected an error on line 2 got %v\n", err)
+	}
+}
+
+func TestNewHTTPRequestsFromList(t *testing.T) {
+	requests, err := NewHTTPRequestsFromList("../test/requests", false)
+	if err != nil {
+		t.Fatalf("NewHTTPRequestsFromList error: %s\n", err)
+	}
+	if len(requests) != 10 {
+		t.Errorf("Expected a request for each .req file got %d\n", len(requests))
+	}
+}
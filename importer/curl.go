@@ -0,0 +1,336 @@
+package importer
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gi0cann/pandushi/fuzzer"
+)
+
+// curlIgnoredOptions are curl options without effect on the request that take an argument
+var curlIgnoredOptions = []string{
+	"-o", "--output", "-x", "--proxy", "-m", "--max-time", "--connect-timeout",
+	"--retry", "-w", "--write-out", "--cacert", "--cert", "--key", "-c", "--cookie-jar",
+	"--resolve", "--limit-rate", "-r", "--range",
+}
+
+// SplitShellWords splits a shell command line into words following the POSIX quoting rules
+// as well as the $'...' quoting used by browsers when copying requests as curl commands
+func SplitShellWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line):
+			i++
+			if line[i] != '\n' {
+				word.WriteByte(line[i])
+				inWord = true
+			}
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return words, errors.New("unterminated single quote")
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '$' && i+1 < len(line) && line[i+1] == '\'':
+			i += 2
+			for ; i < len(line) && line[i] != '\''; i++ {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+					switch line[i] {
+					case 'n':
+						word.WriteByte('\n')
+					case 'r':
+						word.WriteByte('\r')
+					case 't':
+						word.WriteByte('\t')
+					case 'x':
+						if i+2 < len(line) {
+							if b, err := strconv.ParseUint(line[i+1:i+3], 16, 8); err == nil {
+								word.WriteByte(byte(b))
+								i += 2
+								continue
+							}
+						}
+						word.WriteByte('x')
+					default:
+						word.WriteByte(line[i])
+					}
+					continue
+				}
+				word.WriteByte(line[i])
+			}
+			if i >= len(line) {
+				return words, errors.New("unterminated $' quote")
+			}
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte("\"\\$`\n", line[i+1]) >= 0 {
+					i++
+					if line[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(line[i])
+			}
+			if i >= len(line) {
+				return words, errors.New("unterminated double quote")
+			}
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// ParseCurl converts a curl command line, such as one copied from a browser, into a HTTPRequest
+func ParseCurl(command string) (fuzzer.HTTPRequest, error) {
+	words, err := SplitShellWords(command)
+	if err != nil {
+		return fuzzer.HTTPRequest{}, err
+	}
+	if len(words) == 0 || words[0] != "curl" {
+		return fuzzer.HTTPRequest{}, errors.New("not a curl command")
+	}
+
+	method := ""
+	rawurl := ""
+	headers := http.Header{}
+	var data []string
+	var cookies []string
+	var form []formField
+	get := false
+	for i := 1; i < len(words); i++ {
+		word := words[i]
+		next := func() (string, error) {
+			if i+1 >= len(words) {
+				return "", errors.New("missing argument for " + word)
+			}
+			i++
+			return words[i], nil
+		}
+		// Short options may be attached to their argument, e.g. -XPOST
+		if len(word) > 2 && word[0] == '-' && word[1] != '-' && strings.IndexByte("XHdbuAeF", word[1]) >= 0 {
+			words = append(words[:i+1], append([]string{word[2:]}, words[i+1:]...)...)
+			word = word[:2]
+		}
+		var arg string
+		switch word {
+		case "-X", "--request":
+			if arg, err = next(); err == nil {
+				method = strings.ToUpper(arg)
+			}
+		case "-H", "--header":
+			if arg, err = next(); err == nil {
+				parts := strings.SplitN(arg, ":", 2)
+				if len(parts) == 2 {
+					headers.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+				}
+			}
+		case "-d", "--data", "--data-ascii", "--data-binary":
+			if arg, err = next(); err == nil {
+				if strings.HasPrefix(arg, "@") {
+					var content []byte
+					content, err = ioutil.ReadFile(arg[1:])
+					arg = string(content)
+					if word != "--data-binary" {
+						arg = strings.Replace(strings.Replace(arg, "\r", "", -1), "\n", "", -1)
+					}
+				}
+				data = append(data, arg)
+			}
+		case "--data-raw":
+			if arg, err = next(); err == nil {
+				data = append(data, arg)
+			}
+		case "--data-urlencode":
+			if arg, err = next(); err == nil {
+				if eq := strings.Index(arg, "="); eq >= 0 {
+					arg = arg[:eq+1] + url.QueryEscape(arg[eq+1:])
+				} else {
+					arg = url.QueryEscape(arg)
+				}
+				data = append(data, arg)
+			}
+		case "-b", "--cookie":
+			if arg, err = next(); err == nil {
+				cookies = append(cookies, arg)
+			}
+		case "-u", "--user":
+			if arg, err = next(); err == nil {
+				headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(arg)))
+			}
+		case "-A", "--user-agent":
+			if arg, err = next(); err == nil {
+				headers.Set("User-Agent", arg)
+			}
+		case "-e", "--referer":
+			if arg, err = next(); err == nil {
+				headers.Set("Referer", arg)
+			}
+		case "-I", "--head":
+			method = http.MethodHead
+		case "-G", "--get":
+			get = true
+		case "--url":
+			rawurl, err = next()
+		case "-F", "--form":
+			if arg, err = next(); err == nil {
+				var field formField
+				field, err = parseCurlFormField(arg)
+				form = append(form, field)
+			}
+		default:
+			if containsFold(curlIgnoredOptions, word) {
+				_, err = next()
+			} else if !strings.HasPrefix(word, "-") && rawurl == "" {
+				rawurl = word
+			}
+		}
+		if err != nil {
+			return fuzzer.HTTPRequest{}, err
+		}
+	}
+
+	if rawurl == "" {
+		return fuzzer.HTTPRequest{}, errors.New("curl command without URL")
+	}
+	if !strings.Contains(rawurl, "://") {
+		rawurl = "http://" + rawurl
+	}
+	if len(cookies) > 0 && headers.Get("Cookie") == "" {
+		headers.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	var body []byte
+	if len(form) > 0 {
+		if len(data) > 0 {
+			return fuzzer.HTTPRequest{}, errors.New("-F can't be combined with -d")
+		}
+		var contentType string
+		body, contentType, err = multipartBody(form)
+		if err != nil {
+			return fuzzer.HTTPRequest{}, err
+		}
+		headers.Set("Content-Type", contentType)
+		if method == "" {
+			method = http.MethodPost
+		}
+	}
+	if len(data) > 0 {
+		joined := strings.Join(data, "&")
+		if get {
+			separator := "?"
+			if strings.Contains(rawurl, "?") {
+				separator = "&"
+			}
+			rawurl += separator + joined
+		} else {
+			body = []byte(joined)
+			if method == "" {
+				method = http.MethodPost
+			}
+			if headers.Get("Content-Type") == "" {
+				headers.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+		}
+	}
+	if method == "" {
+		method = http.MethodGet
+	}
+	return NewHTTPRequest(method, rawurl, headers, body)
+}
+
+// parseCurlFormField reads a -F argument: name=value, name=<file for a value read from file
+// or name=@file;type=<content type> for a file upload
+func parseCurlFormField(arg string) (formField, error) {
+	eq := strings.Index(arg, "=")
+	if eq <= 0 {
+		return formField{}, errors.New("invalid form field " + arg)
+	}
+	field := formField{Name: arg[:eq], Value: arg[eq+1:]}
+	switch {
+	case strings.HasPrefix(field.Value, "@"):
+		params := strings.Split(field.Value[1:], ";")
+		field.File, field.Value = params[0], ""
+		for _, param := range params[1:] {
+			if strings.HasPrefix(param, "type=") {
+				field.ContentType = strings.TrimPrefix(param, "type=")
+			}
+		}
+	case strings.HasPrefix(field.Value, "<"):
+		content, err := ioutil.ReadFile(strings.Split(field.Value[1:], ";")[0])
+		if err != nil {
+			fmt.Printf("parseCurlFormField form field %s: %s, its value is left empty\n", field.Name, err)
+		}
+		field.Value = string(content)
+	}
+	return field, nil
+}
+
+// SplitCurlCommands splits text into the curl commands it contains, joining lines continued with a backslash
+func SplitCurlCommands(text string) []string {
+	var commands []string
+	var current strings.Builder
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "curl ") && current.Len() > 0 {
+			commands = append(commands, current.String())
+			current.Reset()
+		}
+		if trimmed == "" && current.Len() == 0 {
+			continue
+		}
+		current.WriteString(line + "\n")
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		commands = append(commands, current.String())
+	}
+	return commands
+}
+
+// NewHTTPRequestsFromCurl takes either a curl command or the name of a file containing curl commands and returns their HTTPRequests
+func NewHTTPRequestsFromCurl(commandOrFilename string) ([]fuzzer.HTTPRequest, error) {
+	var requests []fuzzer.HTTPRequest
+	text := commandOrFilename
+	if !strings.HasPrefix(strings.TrimSpace(text), "curl ") {
+		data, err := ioutil.ReadFile(commandOrFilename)
+		if err != nil {
+			return requests, err
+		}
+		text = string(data)
+	}
+	for _, command := range SplitCurlCommands(text) {
+		request, err := ParseCurl(command)
+		if err != nil {
+			return requests, err
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
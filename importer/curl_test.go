@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`curl 'http://localhost/a b' -H "X-A: \"quoted\""`, []string{"curl", "http://localhost/a b", "-H", `X-A: "quoted"`}},
+		{"curl \\\n  -X POST \\\n  http://localhost/", []string{"curl", "-X", "POST", "http://localhost/"}},
+		{`curl $'http://localhost/\'x\'\n' --data-raw $'a=\x41'`, []string{"curl", "http://localhost/'x'\n", "--data-raw", "a=A"}},
+	}
+
+	for _, tt := range tests {
+		words, err := SplitShellWords(tt.input)
+		if err != nil {
+			t.Fatalf("SplitShellWords error: %s\n", err)
+		}
+		if !reflect.DeepEqual(words, tt.expected) {
+			t.Errorf("SplitShellWords(%q) expected: %q got: %q\n", tt.input, tt.expected, words)
+		}
+	}
+}
+
+func TestParseCurl(t *testing.T) {
+	command := `curl 'https://localhost:8443/login.php?next=%2Fhome' \
+  -H 'Accept: text/html' \
+  -H 'Content-Type: application/x-www-form-urlencoded' \
+  -b 'session=abc123' \
+  --data-raw 'user=admin&password=secret' \
+  --compressed \
+  --insecure`
+
+	request, err := ParseCurl(command)
+	if err != nil {
+		t.Fatalf("ParseCurl error: %s\n", err)
+	}
+	r := request.Request
+	if r.Method != "POST" || r.URL.String() != "https://localhost:8443/login.php?next=%2Fhome" || !request.ForceTLS {
+		t.Errorf("Unexpected request line %s %s\n", r.Method, r.URL)
+	}
+	if r.Header.Get("Accept") != "text/html" || r.Header.Get("Cookie") != "session=abc123" {
+		t.Errorf("Unexpected headers %v\n", r.Header)
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	if string(body) != "user=admin&password=secret" {
+		t.Errorf("Unexpected body %s\n", body)
+	}
+	if request.TotalBodyInjectionPoints != 2 || request.TotalCookieInjectionPoints != 1 || request.TotalQueryInjectionPoints != 1 {
+		t.Errorf("Unexpected injection points body: %d cookie: %d query: %d\n", request.TotalBodyInjectionPoints, request.TotalCookieInjectionPoints, request.TotalQueryInjectionPoints)
+	}
+
+	request, err = ParseCurl(`curl -G -XPUT localhost:8009/search -d q=test -u admin:secret`)
+	if err != nil {
+		t.Fatalf("ParseCurl error: %s\n", err)
+	}
+	r = request.Request
+	if r.Method != "PUT" || r.URL.String() != "http://localhost:8009/search?q=test" || r.Header.Get("Authorization") != "Basic YWRtaW46c2VjcmV0" {
+		t.Errorf("Unexpected request %s %s %v\n", r.Method, r.URL, r.Header)
+	}
+
+	dir, err := ioutil.TempDir("", "curl")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	avatar := filepath.Join(dir, "avatar.png")
+	ioutil.WriteFile(avatar, []byte("\x89PNG"), 0644)
+	request, err = ParseCurl(`curl -F 'name=bob' -F 'avatar=@` + avatar + `;type=image/png' -H 'X-Token: t' http://localhost:8009/upload`)
+	if err != nil {
+		t.Fatalf("ParseCurl error: %s\n", err)
+	}
+	r = request.Request
+	if r.Method != "POST" || r.Header.Get("X-Token") != "t" || request.TotalBodyInjectionPoints != 2 {
+		t.Errorf("Unexpected multipart request %s %v with %d body injection points\n", r.Method, r.Header, request.TotalBodyInjectionPoints)
+	}
+	if err := r.ParseMultipartForm(1 << 20); err != nil || r.FormValue("name") != "bob" || len(r.MultipartForm.File["avatar"]) != 1 {
+		t.Fatalf("Expected the form fields in the multipart body got %v (%v)\n", r.MultipartForm, err)
+	}
+	if file := r.MultipartForm.File["avatar"][0]; file.Filename != "avatar.png" || file.Header.Get("Content-Type") != "image/png" {
+		t.Errorf("Unexpected uploaded file %s %v\n", file.Filename, file.Header)
+	}
+	request, err = ParseCurl(`curl -F 'name=bob' -F 'avatar=@/nonexistent/avatar.png' http://localhost:8009/upload`)
+	if err != nil {
+		t.Fatalf("Expected a missing upload file to be left out of the form got %s\n", err)
+	}
+	r = request.Request
+	if err := r.ParseMultipartForm(1 << 20); err != nil || r.FormValue("name") != "bob" || len(r.MultipartForm.File["avatar"]) != 0 {
+		t.Errorf("Expected the multipart body without the missing file got %v (%v)\n", r.MultipartForm, err)
+	}
+
+	commands := SplitCurlCommands("curl http://localhost/a\n\ncurl http://localhost/b \\\n  -H 'X: 1'\n")
+	if len(commands) != 2 {
+		t.Errorf("Expected 2 curl commands got %d: %q\n", len(commands), commands)
+	}
+}
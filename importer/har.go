@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gi0cann/pandushi/fuzzer"
+)
+
+// HAR is a HTTP Archive 1.2 document
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root of a HAR document
+type HARLog struct {
+	Entries []HAREntry `json:"entries"`
+}
+
+// HAREntry is a recorded request/response pair
+type HAREntry struct {
+	StartedDateTime string     `json:"startedDateTime"`
+	Request         HARRequest `json:"request"`
+}
+
+// HARRequest is a recorded request
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []HARNameValue `json:"headers"`
+	Cookies     []HARNameValue `json:"cookies"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+}
+
+// HARNameValue is a header, cookie, or parameter of a recorded request
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is the body of a recorded request
+type HARPostData struct {
+	MimeType string         `json:"mimeType"`
+	Text     string         `json:"text"`
+	Encoding string         `json:"encoding,omitempty"`
+	Params   []HARNameValue `json:"params"`
+}
+
+// Body returns the raw body of the recorded request
+func (p *HARPostData) Body() ([]byte, error) {
+	if p.Text != "" {
+		if p.Encoding == "base64" {
+			return base64.StdEncoding.DecodeString(p.Text)
+		}
+		return []byte(p.Text), nil
+	}
+	if len(p.Params) > 0 {
+		form := url.Values{}
+		for _, param := range p.Params {
+			form.Add(param.Name, param.Value)
+		}
+		return []byte(form.Encode()), nil
+	}
+	return nil, nil
+}
+
+// HTTPRequest converts a recorded request to a HTTPRequest keeping its scheme, headers, cookies and body
+func (r *HARRequest) HTTPRequest() (fuzzer.HTTPRequest, error) {
+	headers := http.Header{}
+	for _, h := range r.Headers {
+		headers.Add(h.Name, h.Value)
+	}
+	if headers.Get("Cookie") == "" && len(r.Cookies) > 0 {
+		var cookies []string
+		for _, c := range r.Cookies {
+			cookies = append(cookies, (&http.Cookie{Name: c.Name, Value: c.Value}).String())
+		}
+		headers.Set("Cookie", strings.Join(cookies, "; "))
+	}
+	var body []byte
+	if r.PostData != nil {
+		var err error
+		body, err = r.PostData.Body()
+		if err != nil {
+			return fuzzer.HTTPRequest{}, err
+		}
+		if headers.Get("Content-Type") == "" && r.PostData.MimeType != "" {
+			headers.Set("Content-Type", r.PostData.MimeType)
+		}
+	}
+	return NewHTTPRequest(r.Method, r.URL, headers, body)
+}
+
+// ParseHAR takes the content of a HAR file and returns a HTTPRequest for each entry
+func ParseHAR(data []byte) ([]fuzzer.HTTPRequest, error) {
+	var har HAR
+	var requests []fuzzer.HTTPRequest
+	err := json.Unmarshal(data, &har)
+	if err != nil {
+		return requests, err
+	}
+	for _, entry := range har.Log.Entries {
+		request, err := entry.Request.HTTPRequest()
+		if err != nil {
+			return requests, err
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+// NewHTTPRequestsFromHARFile reads a HAR file and returns a HTTPRequest for each entry
+func NewHTTPRequestsFromHARFile(filename string) ([]fuzzer.HTTPRequest, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseHAR(data)
+}
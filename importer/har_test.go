@@ -0,0 +1,114 @@
+package importer
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+func TestParseHAR(t *testing.T) {
+	input := `{
+  "log": {
+    "version": "1.2",
+    "entries": [
+      {
+        "request": {
+          "method": "GET",
+          "url": "https://localhost:8443/search.php?q=test&page=1",
+          "httpVersion": "HTTP/2.0",
+          "headers": [
+            {"name": ":authority", "value": "localhost:8443"},
+            {"name": "Host", "value": "localhost:8443"},
+            {"name": "User-Agent", "value": "Mozilla/5.0"}
+          ],
+          "cookies": [
+            {"name": "session", "value": "abc123"},
+            {"name": "lang", "value": "en"}
+          ]
+        }
+      },
+      {
+        "request": {
+          "method": "POST",
+          "url": "http://localhost:8009/login.php",
+          "httpVersion": "HTTP/1.1",
+          "headers": [
+            {"name": "Content-Type", "value": "application/x-www-form-urlencoded"},
+            {"name": "Content-Length", "value": "999"}
+          ],
+          "cookies": [],
+          "postData": {
+            "mimeType": "application/x-www-form-urlencoded",
+            "text": "user=admin&password=secret"
+          }
+        }
+      },
+      {
+        "request": {
+          "method": "POST",
+          "url": "http://localhost:8009/api/items",
+          "headers": [],
+          "postData": {
+            "mimeType": "application/json",
+            "text": "{\"name\":\"foo\",\"count\":1}"
+          }
+        }
+      }
+    ]
+  }
+}`
+
+	requests, err := ParseHAR([]byte(input))
+	if err != nil {
+		t.Fatalf("ParseHAR error: %s\n", err)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 requests got %d\n", len(requests))
+	}
+
+	get := requests[0]
+	if get.Request.URL.Scheme != "https" || !get.ForceTLS {
+		t.Errorf("Expected the https scheme to be kept got %s\n", get.Request.URL.Scheme)
+	}
+	if get.Request.Header.Get(":authority") != "" || len(get.Request.Header["Host"]) != 0 {
+		t.Errorf("Pseudo headers and Host must not be copied to the request headers: %v\n", get.Request.Header)
+	}
+	if get.Request.Header.Get("Cookie") != "session=abc123; lang=en" {
+		t.Errorf("Expected cookies to be added to the request got %s\n", get.Request.Header.Get("Cookie"))
+	}
+	if get.TotalQueryInjectionPoints != 2 || get.TotalCookieInjectionPoints != 2 {
+		t.Errorf("Expected 2 query and 2 cookie injection points got %d and %d\n", get.TotalQueryInjectionPoints, get.TotalCookieInjectionPoints)
+	}
+	if !strings.HasPrefix(get.RequestText, "GET /search.php?q=test&page=1 HTTP/1.1\r\nHost: localhost:8443\r\n") {
+		t.Errorf("Unexpected request text:\n%s\n", get.RequestText)
+	}
+
+	post := requests[1]
+	if post.Request.URL.Scheme != "http" || post.ForceTLS {
+		t.Errorf("Expected the http scheme to be kept got %s\n", post.Request.URL.Scheme)
+	}
+	if post.Request.Header.Get("Content-Length") != "26" {
+		t.Errorf("Expected Content-Length to match the body got %s\n", post.Request.Header.Get("Content-Length"))
+	}
+	if post.TotalBodyInjectionPoints != 2 {
+		t.Errorf("Expected 2 body injection points got %d\n", post.TotalBodyInjectionPoints)
+	}
+	body, _ := ioutil.ReadAll(post.Request.Body)
+	if string(body) != "user=admin&password=secret" {
+		t.Errorf("Unexpected body %s\n", body)
+	}
+
+	json := requests[2]
+	if json.Request.Header.Get("Content-Type") != "application/json" || json.TotalBodyInjectionPoints != 2 {
+		t.Errorf("Expected the postData mime type to be used as Content-Type got %s\n", json.Request.Header.Get("Content-Type"))
+	}
+
+	testcases := get.InjectQueryParameters([]payloads.Payload{payloads.New("XSS", "<script>alert(1)</script>")})
+	for _, tc := range testcases {
+		if tc.Request.Request.URL.Scheme != "https" || tc.Request.Request.Host != "localhost:8443" {
+			t.Errorf("Expected injected requests to keep the https scheme got %s\n", tc.Request.Request.URL)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Package importer converts requests recorded by other tools into fuzzer HTTPRequests
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gi0cann/pandushi/fuzzer"
+)
+
+// skippedHeaders are headers that are managed by the fuzzer itself
+var skippedHeaders = []string{
+	"host",
+	"content-length",
+}
+
+// NewHTTPRequest builds a HTTPRequest from its parts keeping the scheme of rawurl
+func NewHTTPRequest(method string, rawurl string, headers http.Header, body []byte) (fuzzer.HTTPRequest, error) {
+	r, err := http.NewRequest(strings.ToUpper(method), rawurl, bytes.NewReader(body))
+	if err != nil {
+		return fuzzer.HTTPRequest{}, err
+	}
+	for k, v := range headers {
+		// HTTP/2 pseudo headers such as :authority aren't valid HTTP/1.1 headers
+		if strings.HasPrefix(k, ":") || containsFold(skippedHeaders, k) {
+			continue
+		}
+		for _, vv := range v {
+			r.Header.Add(k, vv)
+		}
+	}
+	if len(body) > 0 {
+		r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	return fuzzer.NewHTTPRequestFromRequest(r, r.URL.Scheme == "https"), nil
+}
+
+// formField is a field of a multipart form, the value of file fields is read from File
+type formField struct {
+	Name        string
+	Value       string
+	File        string
+	ContentType string
+}
+
+// multipartBody encodes fields as a multipart/form-data body and returns it with its Content-Type.
+// File fields whose file can't be read are left out of the body.
+func multipartBody(fields []formField) ([]byte, string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, field := range fields {
+		if field.File == "" {
+			err := writer.WriteField(field.Name, field.Value)
+			if err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+		content, err := ioutil.ReadFile(field.File)
+		if err != nil {
+			// exported collections often refer to files of another machine
+			fmt.Printf("multipartBody form field %s: %s, the file is left out of the form\n", field.Name, err)
+			continue
+		}
+		contentType := field.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(field.Name), escapeQuotes(filepath.Base(field.File))))
+		header.Set("Content-Type", contentType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, "", err
+		}
+		part.Write(content)
+	}
+	err := writer.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	return body.Bytes(), writer.FormDataContentType(), nil
+}
+
+// escapeQuotes escapes the quotes and backslashes of a Content-Disposition parameter
+func escapeQuotes(s string) string {
+	return strings.NewReplacer("\\", "\\\\", `"`, "\\\"").Replace(s)
+}
+
+func containsFold(arr []string, item string) bool {
+	for _, v := range arr {
+		if strings.EqualFold(v, item) {
+			return true
+		}
+	}
+	return false
+}
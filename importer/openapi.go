@@ -0,0 +1,513 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gi0cann/pandushi/fuzzer"
+	"gopkg.in/yaml.v2"
+)
+
+// Marker wraps the values of the parameters of generated requests so they are fuzzed as MARKED injection points
+const Marker = "§"
+
+// maxSchemaDepth limits the recursion of schema-derived example values
+const maxSchemaDepth = 8
+
+// openAPIMethods are the operations of a path item in the order requests are generated
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// OpenAPI is the subset of an OpenAPI 3 or Swagger 2 document needed to generate requests
+type OpenAPI struct {
+	OpenAPI string                                `json:"openapi"`
+	Swagger string                                `json:"swagger"`
+	Servers []OpenAPIServer                       `json:"servers"`
+	Host    string                                `json:"host"`
+	Base    string                                `json:"basePath"`
+	Schemes []string                              `json:"schemes"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+
+	Components struct {
+		Schemas       map[string]*OpenAPISchema      `json:"schemas"`
+		Parameters    map[string]*OpenAPIParameter   `json:"parameters"`
+		RequestBodies map[string]*OpenAPIRequestBody `json:"requestBodies"`
+	} `json:"components"`
+	Definitions map[string]*OpenAPISchema    `json:"definitions"`
+	Parameters  map[string]*OpenAPIParameter `json:"parameters"`
+}
+
+// OpenAPIServer is a server hosting the API
+type OpenAPIServer struct {
+	URL       string `json:"url"`
+	Variables map[string]struct {
+		Default string `json:"default"`
+	} `json:"variables"`
+}
+
+// OpenAPIOperation is an API operation
+type OpenAPIOperation struct {
+	OperationID string              `json:"operationId"`
+	Parameters  []*OpenAPIParameter `json:"parameters"`
+	RequestBody *OpenAPIRequestBody `json:"requestBody"`
+	Consumes    []string            `json:"consumes"`
+}
+
+// OpenAPIParameter is a path, query, header, cookie, body or formData parameter of an operation
+type OpenAPIParameter struct {
+	Ref     string         `json:"$ref"`
+	Name    string         `json:"name"`
+	In      string         `json:"in"`
+	Schema  *OpenAPISchema `json:"schema"`
+	Example interface{}    `json:"example"`
+	// Swagger 2 non-body parameters describe their value inline
+	OpenAPISchema
+}
+
+// OpenAPIRequestBody is the body of an operation
+type OpenAPIRequestBody struct {
+	Ref     string                      `json:"$ref"`
+	Content map[string]OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIMediaType is a representation of a request body
+type OpenAPIMediaType struct {
+	Schema  *OpenAPISchema `json:"schema"`
+	Example interface{}    `json:"example"`
+}
+
+// OpenAPISchema is a JSON schema describing a value
+type OpenAPISchema struct {
+	Ref        string                    `json:"$ref"`
+	Type       string                    `json:"type"`
+	Format     string                    `json:"format"`
+	Example    interface{}               `json:"example"`
+	Default    interface{}               `json:"default"`
+	Enum       []interface{}             `json:"enum"`
+	Properties map[string]*OpenAPISchema `json:"properties"`
+	Items      *OpenAPISchema            `json:"items"`
+	AllOf      []*OpenAPISchema          `json:"allOf"`
+	OneOf      []*OpenAPISchema          `json:"oneOf"`
+	AnyOf      []*OpenAPISchema          `json:"anyOf"`
+}
+
+// yamlToJSON converts the maps decoded by yaml.v2 into maps that can be encoded as JSON
+func yamlToJSON(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, value := range vv {
+			m[fmt.Sprint(k)] = yamlToJSON(value)
+		}
+		return m
+	case []interface{}:
+		for i := range vv {
+			vv[i] = yamlToJSON(vv[i])
+		}
+	}
+	return v
+}
+
+// NewOpenAPI parses an OpenAPI 3 or Swagger 2 document in JSON or YAML
+func NewOpenAPI(data []byte) (*OpenAPI, error) {
+	var doc OpenAPI
+	data = bytes.TrimSpace(data)
+	if !bytes.HasPrefix(data, []byte("{")) {
+		var v interface{}
+		err := yaml.Unmarshal(data, &v)
+		if err != nil {
+			return nil, err
+		}
+		data, err = json.Marshal(yamlToJSON(v))
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := json.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if doc.OpenAPI == "" && doc.Swagger == "" {
+		return nil, errors.New("not an OpenAPI or Swagger document")
+	}
+	return &doc, nil
+}
+
+// BaseURL returns the URL of the first server of the document
+func (doc *OpenAPI) BaseURL() string {
+	if len(doc.Servers) > 0 {
+		server := doc.Servers[0].URL
+		for name, variable := range doc.Servers[0].Variables {
+			server = strings.Replace(server, "{"+name+"}", variable.Default, -1)
+		}
+		return server
+	}
+	if doc.Host != "" {
+		scheme := "https"
+		if len(doc.Schemes) > 0 {
+			scheme = doc.Schemes[0]
+		}
+		return scheme + "://" + doc.Host + doc.Base
+	}
+	return ""
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+func (doc *OpenAPI) resolveSchema(s *OpenAPISchema) *OpenAPISchema {
+	for i := 0; s != nil && s.Ref != "" && i < maxSchemaDepth; i++ {
+		name := refName(s.Ref)
+		if next, ok := doc.Components.Schemas[name]; ok {
+			s = next
+		} else {
+			s = doc.Definitions[name]
+		}
+	}
+	return s
+}
+
+func (doc *OpenAPI) resolveParameter(p *OpenAPIParameter) *OpenAPIParameter {
+	if p == nil || p.Ref == "" {
+		return p
+	}
+	name := refName(p.Ref)
+	if next, ok := doc.Components.Parameters[name]; ok {
+		return next
+	}
+	return doc.Parameters[name]
+}
+
+func (doc *OpenAPI) resolveRequestBody(b *OpenAPIRequestBody) *OpenAPIRequestBody {
+	if b == nil || b.Ref == "" {
+		return b
+	}
+	return doc.Components.RequestBodies[refName(b.Ref)]
+}
+
+// exampleFromSchema returns the example, default, first enum value or a value derived from the type of a schema
+func (doc *OpenAPI) exampleFromSchema(s *OpenAPISchema, depth int) interface{} {
+	s = doc.resolveSchema(s)
+	if s == nil || depth > maxSchemaDepth {
+		return nil
+	}
+	if s.Example != nil {
+		return s.Example
+	}
+	if s.Default != nil {
+		return s.Default
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0]
+	}
+	if len(s.AllOf) > 0 {
+		merged := make(map[string]interface{})
+		for _, sub := range s.AllOf {
+			if m, ok := doc.exampleFromSchema(sub, depth+1).(map[string]interface{}); ok {
+				for k, v := range m {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	if len(s.OneOf) > 0 {
+		return doc.exampleFromSchema(s.OneOf[0], depth+1)
+	}
+	if len(s.AnyOf) > 0 {
+		return doc.exampleFromSchema(s.AnyOf[0], depth+1)
+	}
+	switch s.Type {
+	case "integer":
+		return 1
+	case "number":
+		return 1.5
+	case "boolean":
+		return true
+	case "array":
+		return []interface{}{doc.exampleFromSchema(s.Items, depth+1)}
+	case "object", "":
+		if len(s.Properties) == 0 && s.Type == "" {
+			return "test"
+		}
+		m := make(map[string]interface{})
+		for k, v := range s.Properties {
+			m[k] = doc.exampleFromSchema(v, depth+1)
+		}
+		return m
+	}
+	switch s.Format {
+	case "date":
+		return "2020-01-01"
+	case "date-time":
+		return "2020-01-01T00:00:00Z"
+	case "email":
+		return "test@example.com"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "uri", "url":
+		return "https://example.com"
+	}
+	return "test"
+}
+
+func (doc *OpenAPI) parameterValue(p *OpenAPIParameter) string {
+	var v interface{}
+	if p.Example != nil {
+		v = p.Example
+	} else if p.Schema != nil {
+		v = doc.exampleFromSchema(p.Schema, 0)
+	} else {
+		v = doc.exampleFromSchema(&p.OpenAPISchema, 0)
+	}
+	return scalarString(v)
+}
+
+func scalarString(v interface{}) string {
+	switch vv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return vv
+	case float64:
+		return strconv.FormatFloat(vv, 'f', -1, 64)
+	case []interface{}:
+		var parts []string
+		for _, i := range vv {
+			parts = append(parts, scalarString(i))
+		}
+		return strings.Join(parts, ",")
+	case map[string]interface{}:
+		b, _ := json.Marshal(vv)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+func mark(value string) string {
+	return Marker + value + Marker
+}
+
+// markedJSON encodes v as JSON wrapping every scalar value in markers
+func markedJSON(v interface{}, buf *bytes.Buffer) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteString("{")
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			key, _ := json.Marshal(k)
+			buf.Write(key)
+			buf.WriteString(":")
+			markedJSON(vv[k], buf)
+		}
+		buf.WriteString("}")
+	case []interface{}:
+		buf.WriteString("[")
+		for i, item := range vv {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			markedJSON(item, buf)
+		}
+		buf.WriteString("]")
+	case int, int64, float64, bool:
+		// numbers and booleans stay unquoted, InjectMarked quotes the string payloads injected in them
+		buf.WriteString(mark(scalarString(vv)))
+	default:
+		quoted, _ := json.Marshal(scalarString(vv))
+		buf.WriteString(`"` + mark(string(quoted[1:len(quoted)-1])) + `"`)
+	}
+}
+
+var pathParameterRegex = regexp.MustCompile(`\{([^}]+)\}`)
+
+// operationRequest returns the marked raw request text of an operation
+func (doc *OpenAPI) operationRequest(base *url.URL, path string, method string, pathParams []*OpenAPIParameter, op *OpenAPIOperation) string {
+	var query []string
+	var headers []string
+	var cookies []string
+	var formParams []string
+	var body []byte
+	contentType := ""
+
+	params := make(map[string]*OpenAPIParameter)
+	var order []string
+	for _, p := range append(append([]*OpenAPIParameter{}, pathParams...), op.Parameters...) {
+		p = doc.resolveParameter(p)
+		if p == nil {
+			continue
+		}
+		key := p.In + ":" + p.Name
+		if _, ok := params[key]; !ok {
+			order = append(order, key)
+		}
+		params[key] = p
+	}
+
+	for _, key := range order {
+		p := params[key]
+		switch p.In {
+		case "path":
+			path = strings.Replace(path, "{"+p.Name+"}", mark(url.PathEscape(doc.parameterValue(p))), -1)
+		case "query":
+			query = append(query, url.QueryEscape(p.Name)+"="+mark(url.QueryEscape(doc.parameterValue(p))))
+		case "header":
+			headers = append(headers, p.Name+": "+mark(doc.parameterValue(p)))
+		case "cookie":
+			cookies = append(cookies, p.Name+"="+mark(doc.parameterValue(p)))
+		case "formData":
+			formParams = append(formParams, url.QueryEscape(p.Name)+"="+mark(url.QueryEscape(doc.parameterValue(p))))
+		case "body":
+			var buf bytes.Buffer
+			markedJSON(doc.exampleFromSchema(p.Schema, 0), &buf)
+			body = buf.Bytes()
+			contentType = "application/json"
+		}
+	}
+	// Path parameters that aren't described still need a value
+	path = pathParameterRegex.ReplaceAllString(path, mark("1"))
+
+	if len(formParams) > 0 {
+		body = []byte(strings.Join(formParams, "&"))
+		contentType = "application/x-www-form-urlencoded"
+	}
+
+	if requestBody := doc.resolveRequestBody(op.RequestBody); requestBody != nil {
+		mediaTypes := make([]string, 0, len(requestBody.Content))
+		for mediaType := range requestBody.Content {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+		sort.Strings(mediaTypes)
+		for _, mediaType := range mediaTypes {
+			content := requestBody.Content[mediaType]
+			example := content.Example
+			if example == nil {
+				example = doc.exampleFromSchema(content.Schema, 0)
+			}
+			if strings.Contains(mediaType, "json") {
+				var buf bytes.Buffer
+				markedJSON(example, &buf)
+				body = buf.Bytes()
+				contentType = mediaType
+				break
+			}
+			if mediaType == "application/x-www-form-urlencoded" {
+				if m, ok := example.(map[string]interface{}); ok {
+					var fields []string
+					for k, v := range m {
+						fields = append(fields, url.QueryEscape(k)+"="+mark(url.QueryEscape(scalarString(v))))
+					}
+					sort.Strings(fields)
+					body = []byte(strings.Join(fields, "&"))
+					contentType = mediaType
+					break
+				}
+			}
+		}
+	}
+
+	var text bytes.Buffer
+	target := strings.TrimRight(base.Path, "/") + path
+	if !strings.HasPrefix(target, "/") {
+		target = "/" + target
+	}
+	if len(query) > 0 {
+		target += "?" + strings.Join(query, "&")
+	}
+	text.WriteString(strings.ToUpper(method) + " " + target + " HTTP/1.1\r\n")
+	text.WriteString("Host: " + base.Host + "\r\n")
+	for _, h := range headers {
+		text.WriteString(h + "\r\n")
+	}
+	if len(cookies) > 0 {
+		text.WriteString("Cookie: " + strings.Join(cookies, "; ") + "\r\n")
+	}
+	if body != nil {
+		unmarked := strings.Replace(string(body), Marker, "", -1)
+		text.WriteString("Content-Type: " + contentType + "\r\n")
+		text.WriteString("Content-Length: " + strconv.Itoa(len(unmarked)) + "\r\n")
+	}
+	text.WriteString("\r\n")
+	text.Write(body)
+	return text.String()
+}
+
+// HTTPRequests generates a HTTPRequest for each operation of the document.
+// Parameter values are wrapped in markers so they are fuzzed as MARKED injection points.
+// baseURL overrides the servers of the document when it isn't empty.
+func (doc *OpenAPI) HTTPRequests(baseURL string) ([]fuzzer.HTTPRequest, error) {
+	var requests []fuzzer.HTTPRequest
+	if baseURL == "" {
+		baseURL = doc.BaseURL()
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return requests, err
+	}
+	if base.Host == "" {
+		return requests, errors.New("the document doesn't describe an absolute server URL, a base URL is required")
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		item := doc.Paths[path]
+		var pathParams []*OpenAPIParameter
+		if raw, ok := item["parameters"]; ok {
+			err = json.Unmarshal(raw, &pathParams)
+			if err != nil {
+				return requests, err
+			}
+		}
+		for _, method := range openAPIMethods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			var op OpenAPIOperation
+			err = json.Unmarshal(raw, &op)
+			if err != nil {
+				return requests, err
+			}
+			text := doc.operationRequest(base, path, method, pathParams, &op)
+			request, err := fuzzer.NewHTTPRequestFromBytes([]byte(text), base.Scheme == "https")
+			if err != nil {
+				return requests, fmt.Errorf("%s %s: %s", strings.ToUpper(method), path, err)
+			}
+			requests = append(requests, request)
+		}
+	}
+	return requests, nil
+}
+
+// NewHTTPRequestsFromOpenAPIFile reads an OpenAPI 3 or Swagger 2 file and returns a marked HTTPRequest for each operation
+func NewHTTPRequestsFromOpenAPIFile(filename string, baseURL string) ([]fuzzer.HTTPRequest, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := NewOpenAPI(data)
+	if err != nil {
+		return nil, err
+	}
+	return doc.HTTPRequests(baseURL)
+}
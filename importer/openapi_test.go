@@ -0,0 +1,234 @@
+package importer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gi0cann/pandushi/fuzzer"
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+func TestOpenAPIHTTPRequests(t *testing.T) {
+	input := `openapi: 3.0.0
+info:
+  title: Petstore
+  version: 1.0.0
+servers:
+  - url: https://{host}/v1
+    variables:
+      host:
+        default: petstore.local
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            example: 10
+        - $ref: '#/components/parameters/ApiKey'
+    post:
+      operationId: createPet
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: string
+          format: uuid
+    get:
+      operationId: showPetById
+      parameters:
+        - name: session
+          in: cookie
+          schema:
+            type: string
+components:
+  parameters:
+    ApiKey:
+      name: X-Api-Key
+      in: header
+      schema:
+        type: string
+        default: secret
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name:
+          type: string
+          example: doggie
+        age:
+          type: integer
+`
+
+	doc, err := NewOpenAPI([]byte(input))
+	if err != nil {
+		t.Fatalf("NewOpenAPI error: %s\n", err)
+	}
+	requests, err := doc.HTTPRequests("")
+	if err != nil {
+		t.Fatalf("HTTPRequests error: %s\n", err)
+	}
+
+	expected := []string{
+		"GET /v1/pets?limit=§10§ HTTP/1.1\r\nHost: petstore.local\r\nX-Api-Key: §secret§\r\n\r\n",
+		"POST /v1/pets HTTP/1.1\r\nHost: petstore.local\r\nContent-Type: application/json\r\nContent-Length: 25\r\n\r\n{\"age\":§1§,\"name\":\"§doggie§\"}",
+		"GET /v1/pets/§00000000-0000-0000-0000-000000000000§ HTTP/1.1\r\nHost: petstore.local\r\nCookie: session=§test§\r\n\r\n",
+	}
+
+	if len(requests) != len(expected) {
+		t.Fatalf("Expected %d requests got %d\n", len(expected), len(requests))
+	}
+	for i, e := range expected {
+		if requests[i].RequestText != e {
+			t.Errorf("Generated request doesn't match expected request.\nexpected:\n%q\ngot:\n%q\n", e, requests[i].RequestText)
+		}
+		if !requests[i].IsMarked() {
+			t.Errorf("Expected generated request %d to be marked\n", i)
+		}
+		if requests[i].Request.URL.Scheme != "https" {
+			t.Errorf("Expected the https scheme of the server got %s\n", requests[i].Request.URL.Scheme)
+		}
+	}
+
+	testcases := requests[1].InjectMarked([]payloads.Payload{payloads.New("XSS", "<script>alert(1)</script>"), payloads.New("INT", "-1")})
+	if len(testcases) != 4 {
+		t.Fatalf("Expected 4 marked test cases got %d\n", len(testcases))
+	}
+	// the body sent is read from the parsed request, limited to its Content-Length.
+	// string payloads are quoted in the integer field, numbers keep its type
+	expectedBodies := []string{`{"age":"<script>alert(1)</script>","name":"doggie"}`, `{"age":1,"name":"<script>alert(1)</script>"}`, `{"age":-1,"name":"doggie"}`, `{"age":1,"name":"-1"}`}
+	for i, tc := range testcases {
+		body, err := ioutil.ReadAll(tc.Request.Request.Body)
+		if err != nil {
+			t.Fatalf("Error reading the injected body: %s\n", err)
+		}
+		if string(body) != expectedBodies[i] || tc.Request.Request.ContentLength != int64(len(expectedBodies[i])) {
+			t.Errorf("Expected the body %s (%d bytes) got %s (%d bytes)\n", expectedBodies[i], len(expectedBodies[i]), body, tc.Request.Request.ContentLength)
+		}
+		if !json.Valid(body) {
+			t.Errorf("Expected a valid JSON body got %s\n", body)
+		}
+	}
+	var pet map[string]interface{}
+	err = json.Unmarshal([]byte(strings.Replace(requests[1].RequestText[strings.Index(requests[1].RequestText, "\r\n\r\n")+4:], "§", "", -1)), &pet)
+	if err != nil || pet["age"] != float64(1) {
+		t.Errorf("Expected the unmarked body to keep the integer age got %v %v\n", pet, err)
+	}
+}
+
+func TestSwaggerHTTPRequests(t *testing.T) {
+	input := `{
+  "swagger": "2.0",
+  "host": "localhost:8009",
+  "basePath": "/api",
+  "schemes": ["http"],
+  "paths": {
+    "/login": {
+      "post": {
+        "parameters": [
+          {"name": "user", "in": "formData", "type": "string", "default": "admin"},
+          {"name": "password", "in": "formData", "type": "string"}
+        ]
+      }
+    }
+  }
+}`
+
+	doc, err := NewOpenAPI([]byte(input))
+	if err != nil {
+		t.Fatalf("NewOpenAPI error: %s\n", err)
+	}
+	requests, err := doc.HTTPRequests("")
+	if err != nil {
+		t.Fatalf("HTTPRequests error: %s\n", err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request got %d\n", len(requests))
+	}
+	expected := "POST /api/login HTTP/1.1\r\nHost: localhost:8009\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 24\r\n\r\nuser=§admin§&password=§test§"
+	if requests[0].RequestText != expected {
+		t.Errorf("Generated request doesn't match expected request.\nexpected:\n%q\ngot:\n%q\n", expected, requests[0].RequestText)
+	}
+	if requests[0].Request.URL.Scheme != "http" {
+		t.Errorf("Expected the http scheme got %s\n", requests[0].Request.URL.Scheme)
+	}
+}
+
+func TestOpenAPICheckTarget(t *testing.T) {
+	input := `openapi: 3.0.0
+info:
+  title: Petstore
+  version: 1.0.0
+paths:
+  /pets:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name:
+                  type: string
+                  example: doggie
+                age:
+                  type: integer
+                  example: 1
+  /pets/{petId}:
+    get:
+      parameters:
+        - name: petId
+          in: path
+          required: true
+          schema:
+            type: integer
+            example: 7
+`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var pet map[string]interface{}
+			if json.NewDecoder(r.Body).Decode(&pet) != nil || pet["name"] != "doggie" || pet["age"] != float64(1) {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+			return
+		}
+		if r.URL.Path != "/pets/7" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	doc, err := NewOpenAPI([]byte(input))
+	if err != nil {
+		t.Fatalf("NewOpenAPI error: %s\n", err)
+	}
+	requests, err := doc.HTTPRequests(server.URL)
+	if err != nil {
+		t.Fatalf("HTTPRequests error: %s\n", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests got %d\n", len(requests))
+	}
+	for _, request := range requests {
+		if !request.IsMarked() {
+			t.Errorf("Expected a marked request got:\n%s\n", request.RequestText)
+		}
+		if err := fuzzer.CheckTarget(&request, fuzzer.SuccessCodes, nil); err != nil {
+			t.Errorf("Expected the target to accept the marked values of:\n%s\ngot %s\n", request.RequestText, err)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package importer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gi0cann/pandushi/fuzzer"
+)
+
+// PostmanCollection is a Postman v2.0 or v2.1 collection
+type PostmanCollection struct {
+	Info     PostmanInfo       `json:"info"`
+	Item     []PostmanItem     `json:"item"`
+	Variable []PostmanKeyValue `json:"variable"`
+	Auth     *PostmanAuth      `json:"auth"`
+}
+
+// PostmanInfo describes a collection
+type PostmanInfo struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+// PostmanItem is a request or a folder of items
+type PostmanItem struct {
+	Name    string          `json:"name"`
+	Item    []PostmanItem   `json:"item"`
+	Request *PostmanRequest `json:"request"`
+	Auth    *PostmanAuth    `json:"auth"`
+}
+
+// PostmanRequest is a request of a collection
+type PostmanRequest struct {
+	Method string            `json:"method"`
+	Header []PostmanKeyValue `json:"header"`
+	URL    PostmanURL        `json:"url"`
+	Body   *PostmanBody      `json:"body"`
+	Auth   *PostmanAuth      `json:"auth"`
+}
+
+// PostmanKeyValue is a header, query parameter, form field or variable
+type PostmanKeyValue struct {
+	Key         string      `json:"key"`
+	Value       interface{} `json:"value"`
+	Disabled    bool        `json:"disabled"`
+	Type        string      `json:"type"`
+	Src         interface{} `json:"src"`
+	ContentType string      `json:"contentType"`
+}
+
+// String returns the value as a string
+func (kv PostmanKeyValue) String() string {
+	switch v := kv.Value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// source returns the file of a formdata file field, src is either a path or a list of paths
+func (kv PostmanKeyValue) source() string {
+	switch v := kv.Src.(type) {
+	case string:
+		return v
+	case []interface{}:
+		if len(v) > 0 {
+			if path, ok := v[0].(string); ok {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// PostmanURL is the URL of a request which is either a string or an object
+type PostmanURL struct {
+	Raw      string            `json:"raw"`
+	Query    []PostmanKeyValue `json:"query"`
+	Variable []PostmanKeyValue `json:"variable"`
+}
+
+// UnmarshalJSON accepts both the string and the object form of a URL
+func (u *PostmanURL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		u.Raw = raw
+		return nil
+	}
+	type postmanURL PostmanURL
+	return json.Unmarshal(data, (*postmanURL)(u))
+}
+
+// PostmanBody is the body of a request
+type PostmanBody struct {
+	Mode       string            `json:"mode"`
+	Raw        string            `json:"raw"`
+	URLEncoded []PostmanKeyValue `json:"urlencoded"`
+	FormData   []PostmanKeyValue `json:"formdata"`
+	GraphQL    *struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+// PostmanAuth is the authentication of a request, folder or collection
+type PostmanAuth struct {
+	Type   string            `json:"type"`
+	Bearer []PostmanKeyValue `json:"bearer"`
+	Basic  []PostmanKeyValue `json:"basic"`
+	APIKey []PostmanKeyValue `json:"apikey"`
+}
+
+func (a *PostmanAuth) param(params []PostmanKeyValue, key string) string {
+	for _, p := range params {
+		if p.Key == key {
+			return p.String()
+		}
+	}
+	return ""
+}
+
+// PostmanEnvironment is an exported Postman environment
+type PostmanEnvironment struct {
+	Values []struct {
+		Key     string `json:"key"`
+		Value   string `json:"value"`
+		Enabled *bool  `json:"enabled"`
+	} `json:"values"`
+}
+
+var postmanVariableRegex = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// substitute replaces {{variable}} references, unknown variables are left untouched
+func substitute(s string, variables map[string]string) string {
+	for i := 0; i < 5 && postmanVariableRegex.MatchString(s); i++ {
+		s = postmanVariableRegex.ReplaceAllStringFunc(s, func(m string) string {
+			if v, ok := variables[strings.TrimSpace(m[2:len(m)-2])]; ok {
+				return v
+			}
+			return m
+		})
+	}
+	return s
+}
+
+// applyAuth adds the credentials of auth to the headers or query of a request
+func applyAuth(auth *PostmanAuth, headers http.Header, query url.Values, variables map[string]string) {
+	if auth == nil {
+		return
+	}
+	switch auth.Type {
+	case "bearer":
+		headers.Set("Authorization", "Bearer "+substitute(auth.param(auth.Bearer, "token"), variables))
+	case "basic":
+		credentials := substitute(auth.param(auth.Basic, "username"), variables) + ":" + substitute(auth.param(auth.Basic, "password"), variables)
+		headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	case "apikey":
+		key := substitute(auth.param(auth.APIKey, "key"), variables)
+		value := substitute(auth.param(auth.APIKey, "value"), variables)
+		if auth.param(auth.APIKey, "in") == "query" {
+			query.Set(key, value)
+		} else {
+			headers.Set(key, value)
+		}
+	}
+}
+
+// HTTPRequest converts a collection request to a HTTPRequest.
+// auth is the inherited authentication used when the request doesn't define its own.
+func (r *PostmanRequest) HTTPRequest(variables map[string]string, auth *PostmanAuth) (fuzzer.HTTPRequest, error) {
+	rawurl := substitute(r.URL.Raw, variables)
+	for _, v := range r.URL.Variable {
+		rawurl = strings.Replace(rawurl, "/:"+v.Key, "/"+substitute(v.String(), variables), -1)
+	}
+	if !strings.Contains(rawurl, "://") {
+		rawurl = "http://" + rawurl
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return fuzzer.HTTPRequest{}, err
+	}
+	query := u.Query()
+	for _, q := range r.URL.Query {
+		if q.Disabled {
+			query.Del(q.Key)
+		}
+	}
+
+	headers := http.Header{}
+	for _, h := range r.Header {
+		if !h.Disabled {
+			headers.Add(substitute(h.Key, variables), substitute(h.String(), variables))
+		}
+	}
+
+	if r.Auth != nil {
+		auth = r.Auth
+	}
+	applyAuth(auth, headers, query, variables)
+	u.RawQuery = query.Encode()
+
+	var body []byte
+	if r.Body != nil {
+		switch r.Body.Mode {
+		case "raw":
+			body = []byte(substitute(r.Body.Raw, variables))
+			if headers.Get("Content-Type") == "" {
+				switch r.Body.Options.Raw.Language {
+				case "json":
+					headers.Set("Content-Type", "application/json")
+				case "xml":
+					headers.Set("Content-Type", "application/xml")
+				default:
+					headers.Set("Content-Type", "text/plain")
+				}
+			}
+		case "urlencoded":
+			form := url.Values{}
+			for _, f := range r.Body.URLEncoded {
+				if !f.Disabled {
+					form.Add(substitute(f.Key, variables), substitute(f.String(), variables))
+				}
+			}
+			body = []byte(form.Encode())
+			headers.Set("Content-Type", "application/x-www-form-urlencoded")
+		case "graphql":
+			if r.Body.GraphQL != nil {
+				graphql := map[string]interface{}{"query": substitute(r.Body.GraphQL.Query, variables)}
+				var gqlVariables interface{}
+				if json.Unmarshal([]byte(substitute(r.Body.GraphQL.Variables, variables)), &gqlVariables) == nil {
+					graphql["variables"] = gqlVariables
+				}
+				body, _ = json.Marshal(graphql)
+				headers.Set("Content-Type", "application/json")
+			}
+		case "formdata":
+			var form []formField
+			for _, f := range r.Body.FormData {
+				if f.Disabled {
+					continue
+				}
+				field := formField{Name: substitute(f.Key, variables), ContentType: f.ContentType}
+				if f.Type == "file" {
+					field.File = f.source()
+					if field.File == "" {
+						fmt.Printf("PostmanRequest.HTTPRequest form field %s has no file, it is left out of the form\n", field.Name)
+						continue
+					}
+				} else {
+					field.Value = substitute(f.String(), variables)
+				}
+				form = append(form, field)
+			}
+			var contentType string
+			body, contentType, err = multipartBody(form)
+			if err != nil {
+				return fuzzer.HTTPRequest{}, err
+			}
+			headers.Set("Content-Type", contentType)
+		}
+	}
+
+	method := r.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	return NewHTTPRequest(method, u.String(), headers, body)
+}
+
+// collect appends the requests of items and their sub folders
+func (c *PostmanCollection) collect(items []PostmanItem, variables map[string]string, auth *PostmanAuth, requests *[]fuzzer.HTTPRequest) error {
+	for _, item := range items {
+		itemAuth := auth
+		if item.Auth != nil {
+			itemAuth = item.Auth
+		}
+		if item.Request != nil {
+			request, err := item.Request.HTTPRequest(variables, itemAuth)
+			if err != nil {
+				return errors.New(item.Name + ": " + err.Error())
+			}
+			*requests = append(*requests, request)
+		}
+		err := c.collect(item.Item, variables, itemAuth, requests)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HTTPRequests returns a HTTPRequest for each request of the collection.
+// Environment variables override collection variables.
+func (c *PostmanCollection) HTTPRequests(environment map[string]string) ([]fuzzer.HTTPRequest, error) {
+	var requests []fuzzer.HTTPRequest
+	variables := make(map[string]string)
+	for _, v := range c.Variable {
+		if !v.Disabled {
+			variables[v.Key] = v.String()
+		}
+	}
+	for k, v := range environment {
+		variables[k] = v
+	}
+	err := c.collect(c.Item, variables, c.Auth, &requests)
+	return requests, err
+}
+
+// NewPostmanEnvironmentFromFile reads the enabled variables of an exported Postman environment
+func NewPostmanEnvironmentFromFile(filename string) (map[string]string, error) {
+	var env PostmanEnvironment
+	variables := make(map[string]string)
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return variables, err
+	}
+	err = json.Unmarshal(data, &env)
+	if err != nil {
+		return variables, err
+	}
+	for _, v := range env.Values {
+		if v.Enabled == nil || *v.Enabled {
+			variables[v.Key] = v.Value
+		}
+	}
+	return variables, nil
+}
+
+// NewHTTPRequestsFromPostmanFile reads a Postman collection and returns a HTTPRequest for each request
+func NewHTTPRequestsFromPostmanFile(filename string, environment map[string]string) ([]fuzzer.HTTPRequest, error) {
+	var collection PostmanCollection
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &collection)
+	if err != nil {
+		return nil, err
+	}
+	return collection.HTTPRequests(environment)
+}
@@ -0,0 +1,136 @@
+package importer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPostmanHTTPRequests(t *testing.T) {
+	input := `{
+  "info": {"name": "shop", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+  "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}", "type": "string"}]},
+  "variable": [
+    {"key": "baseUrl", "value": "http://localhost:8009"},
+    {"key": "token", "value": "collection-token"}
+  ],
+  "item": [
+    {
+      "name": "items",
+      "item": [
+        {
+          "name": "get item",
+          "request": {
+            "method": "GET",
+            "header": [
+              {"key": "X-Trace", "value": "{{trace}}"},
+              {"key": "X-Disabled", "value": "1", "disabled": true}
+            ],
+            "url": {
+              "raw": "{{baseUrl}}/items/:id?expand=true&debug=1",
+              "query": [{"key": "expand", "value": "true"}, {"key": "debug", "value": "1", "disabled": true}],
+              "variable": [{"key": "id", "value": "42"}]
+            }
+          }
+        },
+        {
+          "name": "create item",
+          "request": {
+            "method": "POST",
+            "auth": {"type": "basic", "basic": [{"key": "username", "value": "admin"}, {"key": "password", "value": "secret"}]},
+            "url": "{{baseUrl}}/items",
+            "body": {"mode": "raw", "raw": "{\"name\": \"{{name}}\"}", "options": {"raw": {"language": "json"}}}
+          }
+        }
+      ]
+    },
+    {
+      "name": "login",
+      "request": {
+        "method": "POST",
+        "auth": {"type": "apikey", "apikey": [{"key": "key", "value": "api_key"}, {"key": "value", "value": "k3y"}, {"key": "in", "value": "query"}]},
+        "url": "{{baseUrl}}/login",
+        "body": {"mode": "urlencoded", "urlencoded": [{"key": "user", "value": "admin"}, {"key": "remember", "value": "1", "disabled": true}]}
+      }
+    },
+    {
+      "name": "upload",
+      "request": {
+        "method": "POST",
+        "url": "{{baseUrl}}/upload",
+        "body": {"mode": "formdata", "formdata": [{"key": "name", "value": "{{name}}", "type": "text"}, {"key": "file", "type": "file", "src": "AVATAR"}, {"key": "off", "value": "1", "type": "text", "disabled": true}]}
+      }
+    }
+  ]
+}`
+
+	dir, err := ioutil.TempDir("", "postman")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	avatar := filepath.Join(dir, "avatar.png")
+	ioutil.WriteFile(avatar, []byte("\x89PNG"), 0644)
+	input = strings.Replace(input, "AVATAR", filepath.ToSlash(avatar), 1)
+
+	var collection PostmanCollection
+	if err := json.Unmarshal([]byte(input), &collection); err != nil {
+		t.Fatalf("Error parsing collection: %s\n", err)
+	}
+	requests, err := collection.HTTPRequests(map[string]string{"trace": "abc", "name": "widget"})
+	if err != nil {
+		t.Fatalf("HTTPRequests error: %s\n", err)
+	}
+	if len(requests) != 4 {
+		t.Fatalf("Expected 4 requests got %d\n", len(requests))
+	}
+
+	get := requests[0].Request
+	if get.URL.String() != "http://localhost:8009/items/42?expand=true" {
+		t.Errorf("Unexpected URL %s\n", get.URL)
+	}
+	if get.Header.Get("X-Trace") != "abc" || get.Header.Get("X-Disabled") != "" {
+		t.Errorf("Unexpected headers %v\n", get.Header)
+	}
+	if get.Header.Get("Authorization") != "Bearer collection-token" {
+		t.Errorf("Expected the collection auth to be inherited got %s\n", get.Header.Get("Authorization"))
+	}
+
+	post := requests[1].Request
+	body, _ := ioutil.ReadAll(post.Body)
+	if string(body) != `{"name": "widget"}` || post.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected body %s with Content-Type %s\n", body, post.Header.Get("Content-Type"))
+	}
+	if post.Header.Get("Authorization") != "Basic YWRtaW46c2VjcmV0" {
+		t.Errorf("Expected basic auth got %s\n", post.Header.Get("Authorization"))
+	}
+	if requests[1].TotalBodyInjectionPoints != 1 {
+		t.Errorf("Expected 1 body injection point got %d\n", requests[1].TotalBodyInjectionPoints)
+	}
+
+	login := requests[2].Request
+	body, _ = ioutil.ReadAll(login.Body)
+	if string(body) != "user=admin" || login.URL.Query().Get("api_key") != "k3y" {
+		t.Errorf("Unexpected login request %s %s\n", login.URL, body)
+	}
+
+	upload := requests[3].Request
+	if err := upload.ParseMultipartForm(1 << 20); err != nil || upload.FormValue("name") != "widget" || upload.FormValue("off") != "" || len(upload.MultipartForm.File["file"]) != 1 {
+		t.Errorf("Expected the formdata fields in the multipart body got %v (%v)\n", upload.MultipartForm, err)
+	}
+
+	for _, src := range []string{filepath.Join(dir, "missing.png"), ""} {
+		collection.Item[2].Request.Body.FormData[1].Src = src
+		requests, err = collection.HTTPRequests(map[string]string{"name": "widget"})
+		if err != nil || len(requests) != 4 {
+			t.Fatalf("Expected 4 requests with the formdata file %q got %d (%v)\n", src, len(requests), err)
+		}
+		upload = requests[3].Request
+		if err := upload.ParseMultipartForm(1 << 20); err != nil || upload.FormValue("name") != "widget" || len(upload.MultipartForm.File["file"]) != 0 {
+			t.Errorf("Expected the formdata fields without the file %q got %v (%v)\n", src, upload.MultipartForm, err)
+		}
+	}
+}
@@ -6,11 +6,14 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
     "net/url"
 
 	"github.com/akamensky/argparse"
 	"github.com/gi0cann/pandushi/fuzzer"
+	"github.com/gi0cann/pandushi/importer"
 	"github.com/gi0cann/pandushi/payloads"
+	"github.com/gi0cann/pandushi/report"
 )
 
 func main() {
@@ -18,7 +21,7 @@ func main() {
 	parser := argparse.NewParser("pandushi", "Pandushi web scanner")
 
 	requestFname := parser.String("r", "request-file", &argparse.Options{Required: false, Help: "Load HTTP request from file"})
-	payloadFname := parser.String("p", "payload-file", &argparse.Options{Required: false, Help: "Load payload file"})
+	payloadFname := parser.String("p", "payload-file", &argparse.Options{Required: false, Help: "Load payload file, one payload per line or a JSON file in the sample/injections_format.json layout with payload metadata"})
 	payloadType := parser.String("t", "payload-type", &argparse.Options{Required: false, Help: "Payload type"})
 	projectName := parser.String("P", "project", &argparse.Options{Required: false, Help: "Project name", Default: "default"})
 	payloadStorageURI := parser.String("x", "payload-storage", &argparse.Options{
@@ -41,68 +44,314 @@ func main() {
 		Required: false,
 		Help:     "List of storage URIs. Supported URIs prefixes are file:// for file storage, and mongodb:// for mongdb.",
 	})
+	harFname := parser.String("H", "har-file", &argparse.Options{Required: false, Help: "Load HTTP requests from a HAR file. A task is created for each entry"})
+	openAPIFname := parser.String("O", "openapi-file", &argparse.Options{Required: false, Help: "Generate HTTP requests for every operation of an OpenAPI 3 or Swagger 2 specification (JSON or YAML). Parameters are fuzzed as marked injection points"})
+	baseURL := parser.String("B", "base-url", &argparse.Options{Required: false, Help: "Base URL of the API, overrides the servers of the OpenAPI specification"})
+	postmanFname := parser.String("M", "postman-collection", &argparse.Options{Required: false, Help: "Load HTTP requests from a Postman v2 collection"})
+	postmanEnvFname := parser.String("E", "postman-environment", &argparse.Options{Required: false, Help: "Postman environment used to substitute the variables of the collection"})
+	curlCommand := parser.String("c", "curl", &argparse.Options{Required: false, Help: "Load HTTP requests from a curl command or a file of curl commands"})
+	requestList := parser.String("R", "request-list", &argparse.Options{Required: false, Help: "Load HTTP requests from a JSONL file or a directory of .req files. A task is created for each request"})
 	forceTLS := parser.Flag("l", "force-tls", &argparse.Options{Required: false, Help: "Force the use TLS/SSL", Default: false})
+	rawMode := parser.Flag("", "raw", &argparse.Options{Required: false, Help: "Send the requests over TCP/TLS, tunneled through --http-proxy with CONNECT, instead of normalizing them with net/http. Use for header injection, CRLF and parser discrepancy payloads. Only marked requests are sent verbatim, the test cases of the other injection points are rebuilt from the parsed request", Default: false})
+	rawInjection := parser.Flag("", "raw-injection", &argparse.Options{Required: false, Help: "Inject payloads as is instead of encoding them for their injection point (url, path, JSON string or header encoding). The requests are still sent with net/http, only header values and paths net/http would refuse or re-encode and unparsable marked requests are sent raw", Default: false})
+	smuggling := parser.Flag("", "smuggling", &argparse.Options{Required: false, Help: "Add CL.TE, TE.CL and TE.TE request smuggling probes to every task. The probes can disrupt other users of the target", Default: false})
+	mutate := parser.Flag("", "mutate", &argparse.Options{Required: false, Help: "Add mutations of the original value of every injection point to every task: bit flips, boundary integers, long strings, format strings, unicode edge cases and deleted values", Default: false})
+	sessionFname := parser.String("", "session", &argparse.Options{Required: false, Help: "JSON session configuration with a login macro, session headers and a session expired detector. Expired sessions are logged in again during the scan"})
+	tokenHooksFname := parser.String("", "token-hooks", &argparse.Options{Required: false, Help: "JSON list of pre-request hooks fetching a page and substituting a fresh token (e.g. anti-CSRF) into every test case"})
+	clientConfig := parser.String("", "client-config", &argparse.Options{Required: false, Help: "JSON HTTP client profile with timeouts, redirect policy, TLS verification, client certificate, HTTP version, keep-alive and DNS overrides. The client flags below override it"})
+	timeout := parser.Int("", "timeout", &argparse.Options{Required: false, Help: "Request timeout in seconds"})
+	redirects := parser.Selector("", "redirects", fuzzer.RedirectPolicies, &argparse.Options{Required: false, Help: "Redirect policy. scope only follows redirects to the host of the request or to --redirect-scope"})
+	redirectScope := parser.StringList("", "redirect-scope", &argparse.Options{Required: false, Help: "Hosts like example.com or *.example.com followed by the scope redirect policy"})
+	verifyTLS := parser.Flag("", "verify-tls", &argparse.Options{Required: false, Help: "Verify the TLS certificates of the targets", Default: false})
+	clientCert := parser.String("", "client-cert", &argparse.Options{Required: false, Help: "PEM client certificate file"})
+	clientKey := parser.String("", "client-key", &argparse.Options{Required: false, Help: "PEM key file of --client-cert"})
+	httpVersion := parser.Selector("", "http-version", []string{fuzzer.HTTPVersionAuto, fuzzer.HTTPVersion11, fuzzer.HTTPVersion2}, &argparse.Options{Required: false, Help: "HTTP version"})
+	keepAlive := parser.Flag("", "keep-alive", &argparse.Options{Required: false, Help: "Reuse connections between test cases", Default: false})
+	maxBodySize := parser.Int("", "max-body-size", &argparse.Options{Required: false, Help: "Response body bytes kept per test case, longer bodies are truncated"})
+	resolve := parser.StringList("", "resolve", &argparse.Options{Required: false, Help: "DNS override like example.com=10.0.0.1 or example.com:443=10.0.0.1"})
+	encoders := parser.StringList("", "encode", &argparse.Options{Required: false, Help: "Payload processors like url|base64 applied to every payload, or XSS=html-entity|prefix:\"> for a payload type. Payloads processed by a pipeline ending with url or double-url aren't url-encoded again in queries, forms and paths. Processors: " + strings.Join(payloads.ProcessorNames(), ", ")})
     proxy := parser.String("s", "http-proxy", &argparse.Options{Required: false, Help: "http proxy format: (http,https)://<address>:<port>"})
 
+	generate := parser.StringList("", "generate", &argparse.Options{Required: false, Help: "Generated payloads like IDOR=range:from=1,to=1000,width=4 added to the stored payloads. Generators: range, charset, dates, product and concat, see payloads generate"})
+	injectionTypes := parser.StringList("", "injection-types", &argparse.Options{Required: false, Help: "Payload types injected by the scans, like XSS or SQLI. Defaults to every type of the payload store"})
+	generateLimit := parser.Int("", "generate-limit", &argparse.Options{Required: false, Help: "Maximum number of payloads produced by the --generate generators of a scan, every payload is kept in memory with its test cases. 0 disables the limit", Default: payloads.DefaultGenerateLimit})
+
+	reportCmd := parser.NewCommand("report", "Render a stored scan as a self-contained HTML report")
+	reportInput := reportCmd.String("i", "input", &argparse.Options{
+		Required: true,
+		Help:     "Storage URI of the scan. Supported URIs prefixes are file:// for file storage, and mongodb:// for mongodb (selects the latest scan matching --project and --scan-name).",
+	})
+	reportOutput := reportCmd.String("o", "output", &argparse.Options{Required: false, Help: "HTML report output file", Default: "report.html"})
+
+	exportCmd := parser.NewCommand("export", "Export a stored scan and its findings for CI pipelines. Exits with 1 when findings reach --fail-on and 2 on errors")
+	exportInput := exportCmd.String("i", "input", &argparse.Options{
+		Required: true,
+		Help:     "Storage URI of the scan. Supported URIs prefixes are file:// for file storage, and mongodb:// for mongodb (selects the latest scan matching --project and --scan-name).",
+	})
+	exportFormat := exportCmd.Selector("f", "format", []string{"sarif", "junit"}, &argparse.Options{Required: false, Help: "Export format", Default: "sarif"})
+	exportOutput := exportCmd.String("o", "output", &argparse.Options{Required: false, Help: "Export output file. Defaults to pandushi.sarif or pandushi.xml"})
+	failOn := exportCmd.Selector("", "fail-on", fuzzer.Severities, &argparse.Options{Required: false, Help: "Minimum finding severity that fails the build", Default: fuzzer.SeverityLow})
+
+	proxyCmd := parser.NewCommand("proxy", "Run an intercepting HTTP(S) proxy that records the proxied requests as seeds")
+	proxyListen := proxyCmd.String("a", "listen", &argparse.Options{Required: false, Help: "Proxy listen address", Default: "127.0.0.1:8080"})
+	proxyOutput := proxyCmd.String("o", "output", &argparse.Options{Required: false, Help: "Seed output. A .jsonl request list or a directory of .req files", Default: "seeds.jsonl"})
+	proxyCADir := proxyCmd.String("d", "ca-dir", &argparse.Options{Required: false, Help: "Directory of the proxy CA certificate and key. A new CA is generated when missing", Default: ".pandushi"})
+	proxyScope := proxyCmd.StringList("", "scope", &argparse.Options{Required: false, Help: "Hosts to record, like example.com or *.example.com. Defaults to every host"})
+	proxyFuzz := proxyCmd.Flag("", "fuzz", &argparse.Options{Required: false, Help: "Queue every recorded request into a Task and fuzz it. Results are stored with --storage-config, file storage writes a <file>_<scan-name>_<n>.json file per Task", Default: false})
+
+	payloadsCmd := parser.NewCommand("payloads", "Manage the payloads of --payload-storage, mongodb://localhost:27017 by default")
+	payloadsListCmd := payloadsCmd.NewCommand("list", "List the payload types with their number of payloads")
+	payloadsSearchCmd := payloadsCmd.NewCommand("search", "Search payload values, of --payload-type when set")
+	payloadsQuery := payloadsSearchCmd.String("q", "query", &argparse.Options{Required: true, Help: "Text searched in the payload values, case insensitive"})
+	payloadsTagCmd := payloadsCmd.NewCommand("tag", "Add tags and a description to a payload")
+	payloadsTagID := payloadsTagCmd.String("i", "id", &argparse.Options{Required: true, Help: "Payload ID, as printed by payloads search"})
+	payloadsTags := payloadsTagCmd.StringList("g", "tag", &argparse.Options{Required: false, Help: "Tag added to the payload"})
+	payloadsDescription := payloadsTagCmd.String("d", "description", &argparse.Options{Required: false, Help: "Payload description"})
+	payloadsDeleteCmd := payloadsCmd.NewCommand("delete", "Delete a payload by --id or every payload of --payload-type")
+	payloadsDeleteID := payloadsDeleteCmd.String("i", "id", &argparse.Options{Required: false, Help: "Payload ID, as printed by payloads search"})
+	payloadsExportCmd := payloadsCmd.NewCommand("export", "Export the payloads of --payload-type in the sample/injections_format.json layout")
+	payloadsExportOutput := payloadsExportCmd.String("o", "output", &argparse.Options{Required: false, Help: "Export output file", Default: "injections.json"})
+	payloadsImportCmd := payloadsCmd.NewCommand("import", "Import a SecLists-style directory tree of payload files, one payload per line, typed by their folder names")
+	payloadsImportInput := payloadsImportCmd.String("i", "input", &argparse.Options{Required: true, Help: "Payload directory"})
+	payloadsMapping := payloadsImportCmd.String("m", "mapping", &argparse.Options{Required: false, Help: "JSON object of folder names and payload types like {\"SQLi\": \"SQLI\"} added to the default mapping. Folders mapped to \"\" are not imported"})
+	payloadsGenerateCmd := payloadsCmd.NewCommand("generate", "Print the payloads of a generator without storing them")
+	payloadsSpec := payloadsGenerateCmd.String("g", "spec", &argparse.Options{Required: true, Help: "Generator like range:from=1,to=1000,step=1,width=4, charset:chars=abc,min=1,max=3, dates:from=2020-01-01,to=2020-12-31,days=1,layout=20060102, product:types=USERS|PASSWORDS,separator=: or concat:types=XSS|SQLI"})
+	payloadsLimit := payloadsGenerateCmd.Int("n", "limit", &argparse.Options{Required: false, Help: "Maximum number of payloads printed, every payload when 0", Default: 0})
+	payloadsExtensions := payloadsImportCmd.StringList("", "extension", &argparse.Options{Required: false, Help: "Payload file extension, .txt and .lst by default"})
+
 	fmt.Println("gscanner")
 	err := parser.Parse(os.Args)
 	if err != nil {
 		fmt.Print(parser.Usage(err))
 	}
 
-	if len(*requestFname) > 0 && len(*storageURIs) > 0 {
-		storageconfig := fuzzer.CreateStorageConfigFromURI(*storageURIs)
-        var proxyURL *url.URL
-        proxyURL = nil
-        if len(*proxy) > 0 {
-            proxyURL, err = url.Parse(*proxy)
-        }
-		fmt.Printf("Request Fname: %s\n", *requestFname)
-		fmt.Printf("Thread Count: %d\n", *threadCount)
-		if len(*errorcodes) > 0 {
-			*errorcodes = append(*errorcodes, fuzzer.SuccessCodes...)
+	if reportCmd.Happened() {
+		task, err := fuzzer.LoadTaskFromURI(*reportInput, *projectName, *scanName)
+		if err != nil {
+			log.Fatalln(err)
 		}
-		fmt.Printf("Allowed %v\n", *errorcodes)
-
-		fd, err := os.Open(*requestFname)
+		err = report.HTMLToFile(*reportOutput, report.Summarize(task))
 		if err != nil {
-			panic(err)
+			log.Fatalln(err)
 		}
-		defer fd.Close()
-
-		text, err := ioutil.ReadAll(fd)
+		fmt.Printf("Report written to %s\n", *reportOutput)
+	} else if exportCmd.Happened() {
+		os.Exit(export(*exportInput, *projectName, *scanName, *exportFormat, *exportOutput, *failOn))
+	} else if payloadsCmd.Happened() {
+		store, err := openPayloadStore(*payloadStorageURI)
 		if err != nil {
-			panic(err)
+			log.Fatalln(err)
+		}
+		defer store.Close()
+		switch {
+		case payloadsListCmd.Happened():
+			err = listPayloadTypes(store)
+		case payloadsSearchCmd.Happened():
+			err = searchPayloads(store, *payloadType, *payloadsQuery)
+		case payloadsTagCmd.Happened():
+			err = tagPayload(store, *payloadsTagID, *payloadsTags, *payloadsDescription)
+		case payloadsDeleteCmd.Happened():
+			err = deletePayloads(store, *payloadType, *payloadsDeleteID)
+		case payloadsExportCmd.Happened():
+			err = exportPayloads(store, *payloadType, *payloadsExportOutput)
+		case payloadsImportCmd.Happened():
+			err = importPayloadDirectory(store, *payloadsImportInput, *payloadsMapping, *payloadsExtensions)
+		case payloadsGenerateCmd.Happened():
+			err = printGenerator(store, *payloadsSpec, *payloadsLimit)
+		default:
+			fmt.Print(payloadsCmd.Usage(nil))
 		}
-
-		//fmt.Printf("%s\n", text)
-
-		request, err := fuzzer.NewHTTPRequestFromBytes(text, *forceTLS)
 		if err != nil {
-			panic(err)
+			log.Fatalln(err)
 		}
-		err = fuzzer.CheckTarget(&request, *errorcodes)
+	} else if proxyCmd.Happened() {
+		profile, err := newClientProfile(clientOptions{*clientConfig, *timeout, *redirects, *redirectScope, *verifyTLS, *clientCert, *clientKey, *httpVersion, *keepAlive, *maxBodySize, *resolve})
 		if err != nil {
-			fmt.Printf("There was an error communication with the target: %s\n", err)
-			os.Exit(1)
+			log.Fatalln(err)
+		}
+		pipelines, err := payloads.ParsePipelines(*encoders)
+		if err != nil {
+			log.Fatalln(err)
 		}
-		request.Request.RequestURI = ""
-		if request.IsMarked() {
-			fmt.Println("Marked")
-			fuzzerTask, err := fuzzer.NewTask(*projectName, *scanName, []string{"XSS"}, []string{"MARKED"}, request, "mongodb://localhost:27017")
+		generators, err := parseGenerators(*generate, *payloadStorageURI, int64(*generateLimit))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		types, err := scanInjectionTypes(*injectionTypes)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		var upstream *url.URL
+		if len(*proxy) > 0 {
+			upstream, err = url.Parse(*proxy)
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}
+		err = runProxy(proxyOptions{
+			Listen:      *proxyListen,
+			Output:      *proxyOutput,
+			CADir:       *proxyCADir,
+			Scope:       *proxyScope,
+			Fuzz:        *proxyFuzz,
+			Project:     *projectName,
+			ScanName:    *scanName,
+			ErrorCodes:  append(*errorcodes, fuzzer.SuccessCodes...),
+			Threads:     *threadCount,
+			StorageURIs: *storageURIs,
+			Upstream:    upstream,
+			Client:      profile,
+			Types:       types,
+			Pipelines:   pipelines,
+			Generators:  generators,
+		})
+		if err != nil {
+			log.Fatalln(err)
+		}
+	} else if (len(*requestFname) > 0 || len(*harFname) > 0 || len(*openAPIFname) > 0 || len(*postmanFname) > 0 || len(*curlCommand) > 0 || len(*requestList) > 0) && len(*storageURIs) > 0 {
+		storageconfig := fuzzer.CreateStorageConfigFromURI(*storageURIs)
+		profile, err := newClientProfile(clientOptions{*clientConfig, *timeout, *redirects, *redirectScope, *verifyTLS, *clientCert, *clientKey, *httpVersion, *keepAlive, *maxBodySize, *resolve})
+		if err != nil {
+			log.Fatalln(err)
+		}
+		pipelines, err := payloads.ParsePipelines(*encoders)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		generators, err := parseGenerators(*generate, *payloadStorageURI, int64(*generateLimit))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		types, err := scanInjectionTypes(*injectionTypes)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("Injection Types: %v\n", types)
+		var proxyURL *url.URL
+		proxyURL = nil
+		if len(*proxy) > 0 {
+			proxyURL, err = url.Parse(*proxy)
+		}
+		fmt.Printf("Thread Count: %d\n", *threadCount)
+		if len(*errorcodes) > 0 {
+			*errorcodes = append(*errorcodes, fuzzer.SuccessCodes...)
+		}
+		fmt.Printf("Allowed %v\n", *errorcodes)
+
+		var requests []fuzzer.HTTPRequest
+		if len(*requestFname) > 0 {
+			fmt.Printf("Request Fname: %s\n", *requestFname)
+			fd, err := os.Open(*requestFname)
 			if err != nil {
 				panic(err)
 			}
-			fuzzerTask.Run(*threadCount, storageconfig, proxyURL)
-		} else {
-			fmt.Println("Not Marked")
-			fuzzerTask, err := fuzzer.NewTask(*projectName, *scanName, []string{"XSS"}, fuzzer.SupportedInjectionPointTypes, request, "mongodb://localhost:27017")
+			defer fd.Close()
+
+			text, err := ioutil.ReadAll(fd)
 			if err != nil {
 				panic(err)
 			}
-			fuzzerTask.Run(*threadCount, storageconfig, proxyURL)
+
+			request, err := fuzzer.NewHTTPRequestFromBytes(text, *forceTLS)
+			if err != nil {
+				panic(err)
+			}
+			requests = append(requests, request)
 		}
-	} else if len(*payloadFname) > 0 && len(*payloadType) > 0 && len(*payloadStorageURI) > 0 {
+		if len(*requestList) > 0 {
+			fmt.Printf("Request List: %s\n", *requestList)
+			listRequests, err := importer.NewHTTPRequestsFromList(*requestList, *forceTLS)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			requests = append(requests, listRequests...)
+		}
+		if len(*harFname) > 0 {
+			fmt.Printf("HAR Fname: %s\n", *harFname)
+			harRequests, err := importer.NewHTTPRequestsFromHARFile(*harFname)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			requests = append(requests, harRequests...)
+		}
+		if len(*openAPIFname) > 0 {
+			fmt.Printf("OpenAPI Fname: %s\n", *openAPIFname)
+			apiRequests, err := importer.NewHTTPRequestsFromOpenAPIFile(*openAPIFname, *baseURL)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			requests = append(requests, apiRequests...)
+		}
+		if len(*postmanFname) > 0 {
+			fmt.Printf("Postman Fname: %s\n", *postmanFname)
+			environment := make(map[string]string)
+			if len(*postmanEnvFname) > 0 {
+				environment, err = importer.NewPostmanEnvironmentFromFile(*postmanEnvFname)
+				if err != nil {
+					log.Fatalln(err)
+				}
+			}
+			postmanRequests, err := importer.NewHTTPRequestsFromPostmanFile(*postmanFname, environment)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			requests = append(requests, postmanRequests...)
+		}
+		if len(*curlCommand) > 0 {
+			curlRequests, err := importer.NewHTTPRequestsFromCurl(*curlCommand)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			requests = append(requests, curlRequests...)
+		}
+
+		var session *fuzzer.Session
+		if len(*sessionFname) > 0 {
+			session, err = fuzzer.NewSessionFromFile(*sessionFname)
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}
+		var hooks []*fuzzer.TokenHook
+		if len(*tokenHooksFname) > 0 {
+			hooks, err = fuzzer.NewTokenHooksFromFile(*tokenHooksFname)
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}
+
+		batch := fuzzer.NewBatch(*projectName)
+		failed := 0
+		for i, request := range requests {
+			name := *scanName
+			if len(requests) > 1 {
+				name = fmt.Sprintf("%s_%d", *scanName, i)
+			}
+			request.Raw = *rawMode
+			request.RawInjection = *rawInjection
+			fuzzerTask, err := newTask(request, *projectName, name, *errorcodes, profile, types, pipelines, generators)
+			if err != nil {
+				fmt.Printf("Scan %s error: %s\n", name, err)
+				failed++
+				continue
+			}
+			fuzzerTask.Session = session
+			fuzzerTask.Hooks = hooks
+			if *smuggling {
+				fuzzerTask.TestCases = append(fuzzerTask.TestCases, fuzzerTask.BaseRequest.InjectSmuggling()...)
+			}
+			if *mutate {
+				fuzzerTask.TestCases = append(fuzzerTask.TestCases, fuzzerTask.BaseRequest.InjectMutations(payloads.DefaultMutators)...)
+			}
+			batch.Add(&fuzzerTask)
+		}
+		if len(batch.Tasks) > 0 {
+			batch.Run(*threadCount, storageconfig, proxyURL)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+	} else if len(*payloadFname) > 0 && (len(*payloadType) > 0 || strings.HasSuffix(strings.ToLower(*payloadFname), ".json")) && len(*payloadStorageURI) > 0 {
 		fmt.Printf("Payload Fname: %s\n", *payloadFname)
 		fmt.Printf("Payload Type: %s\n", *payloadType)
 		fmt.Printf("Payload Storage: %s\n", *payloadStorageURI)
@@ -136,3 +385,87 @@ func main() {
 	}
 
 }
+
+// clientOptions are the HTTP client flags
+type clientOptions struct {
+	Config      string
+	Timeout     int
+	Redirects   string
+	Scope       []string
+	VerifyTLS   bool
+	ClientCert  string
+	ClientKey   string
+	HTTPVersion string
+	KeepAlive   bool
+	MaxBodySize int
+	Resolve     []string
+}
+
+// newClientProfile loads the client profile file of opts when set and applies the client flags on top of it
+func newClientProfile(opts clientOptions) (*fuzzer.ClientProfile, error) {
+	profile := fuzzer.NewClientProfile()
+	if len(opts.Config) > 0 {
+		var err error
+		profile, err = fuzzer.NewClientProfileFromFile(opts.Config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.Timeout > 0 {
+		profile.Timeout = fuzzer.Duration(time.Duration(opts.Timeout) * time.Second)
+	}
+	if len(opts.Redirects) > 0 {
+		profile.Redirects = opts.Redirects
+	}
+	if len(opts.Scope) > 0 {
+		profile.Scope = opts.Scope
+	}
+	if opts.VerifyTLS {
+		profile.VerifyTLS = true
+	}
+	if len(opts.ClientCert) > 0 {
+		profile.ClientCert = opts.ClientCert
+		profile.ClientKey = opts.ClientKey
+	}
+	if len(opts.HTTPVersion) > 0 {
+		profile.HTTPVersion = opts.HTTPVersion
+	}
+	if opts.KeepAlive {
+		profile.KeepAlive = true
+	}
+	if opts.MaxBodySize > 0 {
+		profile.MaxBodySize = int64(opts.MaxBodySize)
+	}
+	if len(opts.Resolve) > 0 {
+		resolve, err := fuzzer.ParseResolve(opts.Resolve)
+		if err != nil {
+			return nil, err
+		}
+		if profile.Resolve == nil {
+			profile.Resolve = make(map[string]string)
+		}
+		for host, ip := range resolve {
+			profile.Resolve[host] = ip
+		}
+	}
+	return profile, profile.Compile()
+}
+
+// newTask checks that the target of request is alive with profile then creates a fuzzer Task injecting the payloads of types for it
+func newTask(request fuzzer.HTTPRequest, projectName string, scanName string, errorcodes []int, profile *fuzzer.ClientProfile, types []string, pipelines payloads.Pipelines, generators payloads.Generators) (fuzzer.Task, error) {
+	err := fuzzer.CheckTarget(&request, errorcodes, profile)
+	if err != nil {
+		return fuzzer.Task{}, fmt.Errorf("there was an error communication with the target: %s", err)
+	}
+	request.Request.RequestURI = ""
+	injectionPointTypes := fuzzer.SupportedInjectionPointTypes
+	if request.IsMarked() {
+		fmt.Println("Marked")
+		injectionPointTypes = []string{"MARKED"}
+	} else {
+		fmt.Println("Not Marked")
+	}
+	task, err := fuzzer.NewTask(projectName, scanName, types, injectionPointTypes, request, payloads.DefaultStoreURI, pipelines, generators)
+	task.Client = profile
+	return task, err
+}
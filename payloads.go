@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gi0cann/pandushi/payloads"
+)
+
+// openPayloadStore opens the payload store of --payload-storage, the scan payload store by default
+func openPayloadStore(storageURI string) (payloads.Store, error) {
+	if storageURI == "" || storageURI == "default" {
+		storageURI = payloads.DefaultStoreURI
+	}
+	return payloads.OpenStore(storageURI)
+}
+
+// scanInjectionTypes returns the payload types injected by scans, every type of the default payload store when none is selected
+func scanInjectionTypes(selected []string) ([]string, error) {
+	if len(selected) > 0 {
+		return selected, nil
+	}
+	store, err := openPayloadStore(payloads.DefaultStoreURI)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	types, err := store.Types()
+	if err != nil {
+		return nil, err
+	}
+	return payloads.SortedTypes(types), nil
+}
+
+// listPayloadTypes prints the payload types of store with their number of payloads
+func listPayloadTypes(store payloads.Store) error {
+	types, err := store.Types()
+	if err != nil {
+		return err
+	}
+	for _, name := range payloads.SortedTypes(types) {
+		fmt.Printf("%s\t%d\n", name, types[name])
+	}
+	return nil
+}
+
+// printPayload prints a payload with its ID, type, tags and description
+func printPayload(payload payloads.Payload) {
+	fmt.Printf("%s\t%s\t%q", payload.ID, payload.InputType, payload.Value)
+	if len(payload.Tags) > 0 {
+		fmt.Printf("\t[%s]", strings.Join(payload.Tags, ", "))
+	}
+	if payload.Description != "" {
+		fmt.Printf("\t%s", payload.Description)
+	}
+	fmt.Println()
+}
+
+// searchPayloads prints the payloads of store containing query, of inputtype when set
+func searchPayloads(store payloads.Store, inputtype string, query string) error {
+	found, err := store.Search(inputtype, query)
+	if err != nil {
+		return err
+	}
+	for _, payload := range found {
+		printPayload(payload)
+	}
+	fmt.Printf("%d payloads found\n", len(found))
+	return nil
+}
+
+// tagPayload adds tags and a description to the payload id
+func tagPayload(store payloads.Store, id string, tags []string, description string) error {
+	err := store.Tag(id, tags, description)
+	if err == nil {
+		fmt.Printf("Tagged payload %s\n", id)
+	}
+	return err
+}
+
+// deletePayloads deletes the payload id, or every payload of inputtype when id is empty
+func deletePayloads(store payloads.Store, inputtype string, id string) error {
+	if id != "" {
+		err := store.Delete(id)
+		if err == nil {
+			fmt.Printf("Deleted payload %s\n", id)
+		}
+		return err
+	}
+	if inputtype == "" {
+		return errors.New("payloads delete needs --id or --payload-type")
+	}
+	deleted, err := store.DeleteType(inputtype)
+	if err == nil {
+		fmt.Printf("Deleted %d %s payloads\n", deleted, strings.ToUpper(inputtype))
+	}
+	return err
+}
+
+// exportPayloads writes the payloads of inputtype to output in the sample/injections_format.json layout
+func exportPayloads(store payloads.Store, inputtype string, output string) error {
+	if inputtype == "" {
+		return errors.New("payloads export needs --payload-type")
+	}
+	exported, err := store.Load([]string{inputtype})
+	if err != nil {
+		return err
+	}
+	outfd, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer outfd.Close()
+	err = payloads.Export(outfd, exported)
+	if err == nil {
+		fmt.Printf("Exported %d %s payloads to %s\n", len(exported), strings.ToUpper(inputtype), output)
+	}
+	return err
+}
+
+// importPayloadDirectory imports the payload files under root into store, typed by mappingFname or the default mapping
+func importPayloadDirectory(store payloads.Store, root string, mappingFname string, extensions []string) error {
+	importer := payloads.NewDirectoryImporter()
+	if mappingFname != "" {
+		mapping, err := payloads.NewTypeMappingFromFile(mappingFname)
+		if err != nil {
+			return err
+		}
+		importer.Mapping = mapping
+	}
+	if len(extensions) > 0 {
+		importer.Extensions = extensions
+	}
+	report, err := importer.Import(root, store)
+	if err != nil {
+		return err
+	}
+	for _, name := range payloads.SortedTypes(report.Types) {
+		fmt.Printf("%s\t%d\n", name, report.Types[name])
+	}
+	for _, file := range report.Unmapped {
+		fmt.Printf("No payload type for %s\n", file)
+	}
+	fmt.Printf("Read %d files, inserted %d payloads, skipped %d duplicate payloads and %d blank lines\n", report.Files, report.Inserted, report.Skipped.Duplicates, report.Skipped.Blank)
+	return nil
+}
+
+// parseGenerators reads the --generate specs limited to limit payloads, product and concat load their payload types from the payload store of storageURI
+func parseGenerators(specs []string, storageURI string, limit int64) (payloads.Generators, error) {
+	load := func(InputTypes []string) ([]payloads.Payload, error) {
+		store, err := openPayloadStore(storageURI)
+		if err != nil {
+			return nil, err
+		}
+		defer store.Close()
+		return store.Load(InputTypes)
+	}
+	return payloads.ParseGenerators(specs, load, limit)
+}
+
+// printGenerator prints the values of the generator spec, at most limit values when limit is set
+func printGenerator(store payloads.Store, spec string, limit int) error {
+	generator, err := payloads.ParseGenerator(spec, store.Load)
+	if err != nil {
+		return err
+	}
+	printed := 0
+	payloads.Each(generator, func(value string) bool {
+		fmt.Println(value)
+		printed++
+		return limit <= 0 || printed < limit
+	})
+	return nil
+}
@@ -0,0 +1,90 @@
+package payloads
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+)
+
+// NormalizeType returns the stored form of a payload type
+func NormalizeType(inputtype string) string {
+	return strings.ToUpper(strings.TrimSpace(inputtype))
+}
+
+// NormalizeValue removes the byte order mark and the carriage return left on payload values by CRLF files
+func NormalizeValue(value string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(value, "\ufeff"), "\r")
+}
+
+// Key identifies a payload by its normalized type and value
+func (P Payload) Key() string {
+	return NormalizeType(P.InputType) + "\x00" + NormalizeValue(P.Value)
+}
+
+// Skipped counts the payloads left out of an import or a load
+type Skipped struct {
+	Blank      int // Empty or whitespace only values
+	Duplicates int // Payloads with the Key of a previous payload
+}
+
+// Total returns the number of skipped payloads
+func (S Skipped) Total() int {
+	return S.Blank + S.Duplicates
+}
+
+// Dedup returns the payloads normalized without blank values and duplicates, keeping the first payload of each Key
+func Dedup(payloads []Payload) ([]Payload, Skipped) {
+	var skipped Skipped
+	seen := make(map[string]bool)
+	unique := make([]Payload, 0, len(payloads))
+	for _, payload := range payloads {
+		payload.InputType = NormalizeType(payload.InputType)
+		payload.Value = NormalizeValue(payload.Value)
+		if strings.TrimSpace(payload.Value) == "" {
+			skipped.Blank++
+			continue
+		}
+		if seen[payload.Key()] {
+			skipped.Duplicates++
+			continue
+		}
+		seen[payload.Key()] = true
+		unique = append(unique, payload)
+	}
+	return unique, skipped
+}
+
+// ParseLines returns the payloads of type payloadType read one per line from text, without blank lines and duplicates
+func ParseLines(payloadType string, text string) ([]Payload, Skipped) {
+	var lines []Payload
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		lines = append(lines, New(payloadType, line))
+	}
+	return Dedup(lines)
+}
+
+// readJSONFile reads the payloads of a JSON payload file, previous imports appended to the file as separate arrays are read too
+func readJSONFile(filename string) ([]Payload, error) {
+	var payloads []Payload
+	fd, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return payloads, nil
+	}
+	if err != nil {
+		return payloads, err
+	}
+	defer fd.Close()
+	decoder := json.NewDecoder(fd)
+	for {
+		var batch []Payload
+		err := decoder.Decode(&batch)
+		if err == io.EOF {
+			return payloads, nil
+		}
+		if err != nil {
+			return payloads, err
+		}
+		payloads = append(payloads, batch...)
+	}
+}
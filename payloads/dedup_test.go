@@ -0,0 +1,56 @@
+package payloads
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseLines(t *testing.T) {
+	payloads, skipped := ParseLines("xss", "\ufeff<script>\r\n<img>\r\n\r\n<script>\n  \n<IMG>\n")
+	expected := []string{"<script>", "<img>", "<IMG>"}
+	if len(payloads) != len(expected) {
+		t.Fatalf("Expected %d payloads got %+v\n", len(expected), payloads)
+	}
+	for i, payload := range payloads {
+		if payload.Value != expected[i] || payload.InputType != "XSS" {
+			t.Errorf("Expected XSS %q got %s %q\n", expected[i], payload.InputType, payload.Value)
+		}
+	}
+	if skipped.Duplicates != 1 || skipped.Blank != 2 {
+		t.Errorf("Expected 1 duplicate and 2 blank lines got %+v\n", skipped)
+	}
+}
+
+func TestDedup(t *testing.T) {
+	payloads, skipped := Dedup([]Payload{New("xss", "a"), New("XSS", "a\r"), New("SQLI", "a"), New("sqli", "")})
+	if len(payloads) != 2 || skipped.Duplicates != 1 || skipped.Blank != 1 || skipped.Total() != 2 {
+		t.Errorf("Expected 2 payloads with 1 duplicate and 1 blank value got %+v %+v\n", payloads, skipped)
+	}
+}
+
+func TestNewPayloadsFromFileToJSONFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "payloads")
+	if err != nil {
+		t.Fatalf("Error creating temporary directory: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	input := filepath.Join(dir, "xss.txt")
+	output := filepath.Join(dir, "payloads.json")
+
+	for _, lines := range []string{"<script>\n<img>\n", "<img>\n<svg>\n\n"} {
+		err = ioutil.WriteFile(input, []byte(lines), 0644)
+		if err != nil {
+			t.Fatalf("Error writing payload file: %s\n", err)
+		}
+		_, err = NewPayloadsFromFileToJSONFile("xss", input, output)
+		if err != nil {
+			t.Fatalf("Error importing payloads: %s\n", err)
+		}
+	}
+	stored, err := readJSONFile(output)
+	if err != nil || len(stored) != 3 {
+		t.Errorf("Expected 3 stored payloads got %+v (%v)\n", stored, err)
+	}
+}
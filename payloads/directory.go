@@ -0,0 +1,228 @@
+package payloads
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TypeMapping maps the folder and file names of a payload directory tree to payload types.
+// Names are matched lower case with spaces and underscores read as dashes, names mapped to "" are not imported.
+type TypeMapping map[string]string
+
+// DefaultTypeMapping maps the usual SecLists and PayloadsAllTheThings folder names to payload types
+var DefaultTypeMapping = TypeMapping{
+	"xss":                  "XSS",
+	"cross-site-scripting": "XSS",
+	"sqli":                 "SQLI",
+	"sql":                  "SQLI",
+	"sql-injection":        "SQLI",
+	"nosql":                "NOSQLI",
+	"nosql-injection":      "NOSQLI",
+	"lfi":                  "LFI",
+	"file-inclusion":       "LFI",
+	"traversal":            "PATH_TRAVERSAL",
+	"path-traversal":       "PATH_TRAVERSAL",
+	"directory-traversal":  "PATH_TRAVERSAL",
+	"command-injection":    "CMDI",
+	"cmdi":                 "CMDI",
+	"os-command-injection": "CMDI",
+	"xxe":                  "XXE",
+	"xxe-injection":        "XXE",
+	"xml":                  "XXE",
+	"ssti":                 "SSTI",
+	"template-injection":   "SSTI",
+	"server-side-template": "SSTI",
+	"ssrf":                 "SSRF",
+	"server-side-request":  "SSRF",
+	"ldap":                 "LDAP",
+	"ldap-injection":       "LDAP",
+	"xpath":                "XPATH",
+	"xpath-injection":      "XPATH",
+	"crlf":                 "CRLF",
+	"crlf-injection":       "CRLF",
+	"open-redirect":        "OPEN_REDIRECT",
+	"redirect":             "OPEN_REDIRECT",
+	"format-strings":       "FORMAT_STRING",
+	"format-string":        "FORMAT_STRING",
+}
+
+// NewTypeMappingFromFile reads a JSON object of folder names and payload types added to DefaultTypeMapping
+func NewTypeMappingFromFile(filename string) (TypeMapping, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var custom TypeMapping
+	err = json.Unmarshal(data, &custom)
+	if err != nil {
+		return nil, err
+	}
+	mapping := make(TypeMapping)
+	for name, inputtype := range DefaultTypeMapping {
+		mapping[name] = inputtype
+	}
+	for name, inputtype := range custom {
+		mapping[mappingName(name)] = NormalizeType(inputtype)
+	}
+	return mapping, nil
+}
+
+// mappingName returns the TypeMapping key of a folder or file name
+func mappingName(name string) string {
+	return strings.NewReplacer(" ", "-", "_", "-").Replace(strings.ToLower(name))
+}
+
+// Type returns the payload type of a file from its folders, deepest first, then from the names made of the words of its file name.
+// It returns false for files without a type or under a folder mapped to "".
+func (M TypeMapping) Type(relpath string) (string, bool) {
+	parts := strings.Split(filepath.ToSlash(filepath.Dir(relpath)), "/")
+	for _, part := range parts {
+		if inputtype, ok := M[mappingName(part)]; ok && inputtype == "" {
+			return "", false
+		}
+	}
+	for i := len(parts) - 1; i >= 0; i-- {
+		if inputtype, ok := M[mappingName(parts[i])]; ok {
+			return inputtype, true
+		}
+	}
+	// files like Fuzzing/XSS-Jhaddix.txt, longer names first so sql-injection wins over sql
+	words := nameWords(strings.TrimSuffix(filepath.Base(relpath), filepath.Ext(relpath)))
+	names := make([]string, 0, len(M))
+	for name := range M {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		if M[name] != "" && containsWords(words, nameWords(name)) {
+			return M[name], true
+		}
+	}
+	return "", false
+}
+
+// nameWords splits a lower cased name on the characters that aren't letters or digits
+func nameWords(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// containsWords reports whether words contains the sequence of words sub, so sql matches sql-users.txt but not mysql-users.txt
+func containsWords(words []string, sub []string) bool {
+	if len(sub) == 0 {
+		return false
+	}
+	for i := 0; i+len(sub) <= len(words); i++ {
+		match := true
+		for j := range sub {
+			if words[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultExtensions are the payload file extensions read by a DirectoryImporter
+var DefaultExtensions = []string{".txt", ".lst"}
+
+// DirectoryImporter reads payload files from a directory tree like SecLists, one payload per line
+type DirectoryImporter struct {
+	Mapping    TypeMapping
+	Extensions []string // File extensions read, every file when empty
+}
+
+// NewDirectoryImporter returns a DirectoryImporter with the default mapping and extensions
+func NewDirectoryImporter() *DirectoryImporter {
+	return &DirectoryImporter{Mapping: DefaultTypeMapping, Extensions: DefaultExtensions}
+}
+
+// DirectoryReport summarizes a directory import
+type DirectoryReport struct {
+	Files    int            // Payload files read
+	Unmapped []string       // Files without a payload type, relative to the root
+	Types    map[string]int // Payloads read of each type, without duplicates
+	Inserted int            // Payloads added to the store
+	Skipped  Skipped        // Blank lines and duplicates in the tree or already stored
+}
+
+// readable reports whether path has one of the Extensions of the importer
+func (D *DirectoryImporter) readable(path string) bool {
+	if len(D.Extensions) == 0 {
+		return true
+	}
+	for _, extension := range D.Extensions {
+		if strings.EqualFold(filepath.Ext(path), extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// Read returns the payloads of the files under root without blank lines and duplicates.
+// The name of root is looked up with the folders of the files, so a root like SecLists/Fuzzing/SQLi types its files.
+func (D *DirectoryImporter) Read(root string) ([]Payload, DirectoryReport, error) {
+	var payloads []Payload
+	report := DirectoryReport{Types: make(map[string]int)}
+	rootName := filepath.Base(root)
+	if absolute, err := filepath.Abs(root); err == nil {
+		rootName = filepath.Base(absolute)
+	}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !D.readable(path) {
+			return err
+		}
+		relpath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		inputtype, ok := D.Mapping.Type(filepath.Join(rootName, relpath))
+		if !ok {
+			report.Unmapped = append(report.Unmapped, relpath)
+			return nil
+		}
+		text, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		lines, skipped := ParseLines(inputtype, string(text))
+		report.Files++
+		report.Skipped.Blank += skipped.Blank
+		report.Skipped.Duplicates += skipped.Duplicates
+		payloads = append(payloads, lines...)
+		return nil
+	})
+	payloads, skipped := Dedup(payloads)
+	report.Skipped.Duplicates += skipped.Duplicates
+	for _, payload := range payloads {
+		report.Types[payload.InputType]++
+	}
+	return payloads, report, err
+}
+
+// Import adds the payloads of the files under root to store
+func (D *DirectoryImporter) Import(root string, store Store) (DirectoryReport, error) {
+	payloads, report, err := D.Read(root)
+	if err != nil {
+		return report, err
+	}
+	inserted, skipped, err := store.Add(payloads)
+	report.Inserted = inserted
+	report.Skipped.Duplicates += skipped.Duplicates
+	return report, err
+}
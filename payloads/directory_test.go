@@ -0,0 +1,96 @@
+package payloads
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTypeMapping(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+		ok       bool
+	}{
+		{"Fuzzing/SQLi/Generic-SQLi.txt", "SQLI", true},
+		{"Fuzzing/SQLi/quick/list.txt", "SQLI", true},
+		{"Fuzzing/XSS/human-friendly/XSS-Jhaddix.txt", "XSS", true},
+		{"Fuzzing/command-injection-commix.txt", "CMDI", true},
+		{"Fuzzing/nosql.txt", "NOSQLI", true},
+		{"Fuzzing/LFI/LFI-Jhaddix.txt", "LFI", true},
+		{"Server Side Template Injection/Intruder/ssti.txt", "SSTI", true},
+		{"Fuzzing/big-list-of-naughty-strings.txt", "", false},
+		{"Fuzzing/mysql-users.txt", "", false},
+		{"Fuzzing/sql_users.txt", "SQLI", true},
+		{"Fuzzing/XSSPolyglots.txt", "", false},
+	}
+	for _, test := range tests {
+		inputtype, ok := DefaultTypeMapping.Type(filepath.FromSlash(test.path))
+		if inputtype != test.expected || ok != test.ok {
+			t.Errorf("Expected %s (%t) for %s got %s (%t)\n", test.expected, test.ok, test.path, inputtype, ok)
+		}
+	}
+}
+
+func TestDirectoryImporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "payloads")
+	if err != nil {
+		t.Fatalf("Error creating temporary directory: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"corpus/Fuzzing/SQLi/Generic-SQLi.txt":   "' or 1=1--\n\" or 1=1--\n\n",
+		"corpus/Fuzzing/SQLi/quick-SQLi.txt":     "' or 1=1--\nadmin'--\n",
+		"corpus/Fuzzing/XSS/XSS-Jhaddix.txt":     "<script>alert(1)</script>\r\n<svg/onload=alert(1)>\r\n",
+		"corpus/Fuzzing/XSS/README.md":           "not a payload list\n",
+		"corpus/Fuzzing/Unknown/strings.txt":     "a\n",
+		"corpus/Fuzzing/UserAgents/mobile.txt":   "Mozilla/5.0\n",
+		"corpus/Fuzzing/Private/SQLi/drafts.txt": "waitfor delay '0:0:5'\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		err = ioutil.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("Error writing %s: %s\n", name, err)
+		}
+	}
+	mappingFile := filepath.Join(dir, "mapping.json")
+	ioutil.WriteFile(mappingFile, []byte(`{"Unknown": "fuzz", "Private": ""}`), 0644)
+
+	importer := NewDirectoryImporter()
+	importer.Mapping, err = NewTypeMappingFromFile(mappingFile)
+	if err != nil {
+		t.Fatalf("Error reading mapping: %s\n", err)
+	}
+	store := &FileStore{Filename: filepath.Join(dir, "payloads.json")}
+	store.Add([]Payload{New("XSS", "<script>alert(1)</script>")})
+
+	report, err := importer.Import(filepath.Join(dir, "corpus"), store)
+	if err != nil {
+		t.Fatalf("Error importing directory: %s\n", err)
+	}
+	if report.Files != 4 || len(report.Unmapped) != 2 {
+		t.Errorf("Expected 4 files read and 2 unmapped files got %d %v\n", report.Files, report.Unmapped)
+	}
+	if report.Types["SQLI"] != 3 || report.Types["XSS"] != 2 || report.Types["FUZZ"] != 1 {
+		t.Errorf("Expected 3 SQLI, 2 XSS and 1 FUZZ payloads got %v\n", report.Types)
+	}
+	if report.Inserted != 5 || report.Skipped.Duplicates != 2 || report.Skipped.Blank != 1 {
+		t.Errorf("Expected 5 payloads inserted with 2 duplicates and 1 blank line got %d %+v\n", report.Inserted, report.Skipped)
+	}
+	types, _ := store.Types()
+	if types["SQLI"] != 3 || types["XSS"] != 2 || types["FUZZ"] != 1 {
+		t.Errorf("Expected 3 SQLI, 2 XSS and 1 FUZZ stored payloads got %v\n", types)
+	}
+
+	// the files of a root named after a payload type get its type
+	polyglots := filepath.Join(dir, "XSS", "polyglots.txt")
+	os.MkdirAll(filepath.Dir(polyglots), 0755)
+	ioutil.WriteFile(polyglots, []byte("jaVasCript:alert(1)\n"), 0644)
+	payloads, report, err := NewDirectoryImporter().Read(filepath.Join(dir, "XSS"))
+	if err != nil || len(payloads) != 1 || payloads[0].InputType != "XSS" || len(report.Unmapped) != 0 {
+		t.Errorf("Expected the XSS payload of the XSS root got %v %v (%v)\n", payloads, report.Unmapped, err)
+	}
+}
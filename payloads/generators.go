@@ -0,0 +1,502 @@
+package payloads
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Generator lazily produces payload values, Reset starts it over from its first value
+type Generator interface {
+	Next() (string, bool)
+	Reset()
+	Size() int64 // Number of values produced, -1 when larger than an int64
+}
+
+// Each calls fn with the values of generator until fn returns false or the generator is exhausted
+func Each(generator Generator, fn func(value string) bool) {
+	generator.Reset()
+	for value, ok := generator.Next(); ok; value, ok = generator.Next() {
+		if !fn(value) {
+			return
+		}
+	}
+}
+
+// rangeGenerator produces the integers from start to end
+type rangeGenerator struct {
+	start, end, step int64
+	width            int
+	current          int64
+}
+
+// Range returns a Generator of the integers from start to end included, every step, zero padded to width digits.
+// The range counts down when end is lower than start.
+func Range(start int64, end int64, step int64, width int) (Generator, error) {
+	if step == 0 {
+		return nil, fmt.Errorf("range step must not be 0")
+	}
+	if step < 0 {
+		step = -step
+	}
+	if end < start {
+		step = -step
+	}
+	return &rangeGenerator{start: start, end: end, step: step, width: width, current: start}, nil
+}
+
+func (G *rangeGenerator) Next() (string, bool) {
+	if (G.step > 0 && G.current > G.end) || (G.step < 0 && G.current < G.end) {
+		return "", false
+	}
+	value := G.current
+	G.current += G.step
+	if value < 0 {
+		return fmt.Sprintf("-%0*d", G.width, -value), true
+	}
+	return fmt.Sprintf("%0*d", G.width, value), true
+}
+
+func (G *rangeGenerator) Reset() {
+	G.current = G.start
+}
+
+func (G *rangeGenerator) Size() int64 {
+	distance := G.end - G.start
+	if (G.step > 0) != (distance >= 0) && distance != 0 {
+		return -1
+	}
+	return distance/G.step + 1
+}
+
+// charsetGenerator produces every string of the characters of a charset by increasing length
+type charsetGenerator struct {
+	charset  []rune
+	min, max int
+	indexes  []int
+	done     bool
+}
+
+// Charset returns a Generator of every string of min to max characters of charset, shortest first
+func Charset(charset string, min int, max int) (Generator, error) {
+	runes := []rune(charset)
+	if len(runes) == 0 || min < 0 || max < min {
+		return nil, fmt.Errorf("charset needs characters and lengths 0 <= min <= max")
+	}
+	G := &charsetGenerator{charset: runes, min: min, max: max}
+	G.Reset()
+	return G, nil
+}
+
+func (G *charsetGenerator) Next() (string, bool) {
+	if G.done {
+		return "", false
+	}
+	value := make([]rune, len(G.indexes))
+	for i, index := range G.indexes {
+		value[i] = G.charset[index]
+	}
+	// increment the indexes like an odometer, growing to the next length when they wrap
+	i := len(G.indexes) - 1
+	for ; i >= 0; i-- {
+		G.indexes[i]++
+		if G.indexes[i] < len(G.charset) {
+			break
+		}
+		G.indexes[i] = 0
+	}
+	if i < 0 {
+		if len(G.indexes) == G.max {
+			G.done = true
+		} else {
+			G.indexes = make([]int, len(G.indexes)+1)
+		}
+	}
+	return string(value), true
+}
+
+func (G *charsetGenerator) Reset() {
+	G.indexes = make([]int, G.min)
+	G.done = false
+}
+
+func (G *charsetGenerator) Size() int64 {
+	const maxSize = 1<<63 - 1
+	var size, count int64 = 0, 1
+	for length := 0; length <= G.max; length++ {
+		if length >= G.min {
+			if size > maxSize-count {
+				return -1
+			}
+			size += count
+		}
+		if length < G.max {
+			if count > maxSize/int64(len(G.charset)) {
+				return -1
+			}
+			count *= int64(len(G.charset))
+		}
+	}
+	return size
+}
+
+// dateGenerator produces the dates from start to end
+type dateGenerator struct {
+	start, end time.Time
+	days       int
+	layout     string
+	current    time.Time
+}
+
+// Dates returns a Generator of the dates from start to end included every days, formatted with a time layout
+func Dates(start time.Time, end time.Time, days int, layout string) (Generator, error) {
+	if days <= 0 || end.Before(start) {
+		return nil, fmt.Errorf("dates need a positive number of days and an end after the start")
+	}
+	return &dateGenerator{start: start, end: end, days: days, layout: layout, current: start}, nil
+}
+
+func (G *dateGenerator) Next() (string, bool) {
+	if G.current.After(G.end) {
+		return "", false
+	}
+	value := G.current
+	G.current = G.current.AddDate(0, 0, G.days)
+	return value.Format(G.layout), true
+}
+
+func (G *dateGenerator) Reset() {
+	G.current = G.start
+}
+
+func (G *dateGenerator) Size() int64 {
+	var size int64
+	for date := G.start; !date.After(G.end); date = date.AddDate(0, 0, G.days) {
+		size++
+	}
+	return size
+}
+
+// listGenerator produces the values of a list
+type listGenerator struct {
+	values []string
+	index  int
+}
+
+// List returns a Generator of values
+func List(values []string) Generator {
+	return &listGenerator{values: values}
+}
+
+// FromPayloads returns a Generator of the values of payloads
+func FromPayloads(payloads []Payload) Generator {
+	values := make([]string, len(payloads))
+	for i, payload := range payloads {
+		values[i] = payload.Value
+	}
+	return List(values)
+}
+
+func (G *listGenerator) Next() (string, bool) {
+	if G.index >= len(G.values) {
+		return "", false
+	}
+	G.index++
+	return G.values[G.index-1], true
+}
+
+func (G *listGenerator) Reset() {
+	G.index = 0
+}
+
+func (G *listGenerator) Size() int64 {
+	return int64(len(G.values))
+}
+
+// productGenerator produces the Cartesian product of generators
+type productGenerator struct {
+	generators []Generator
+	separator  string
+	current    []string
+	started    bool
+	done       bool
+}
+
+// Product returns a Generator of every combination of the values of generators joined with separator, the last generator varies first
+func Product(separator string, generators ...Generator) Generator {
+	return &productGenerator{generators: generators, separator: separator}
+}
+
+func (G *productGenerator) Next() (string, bool) {
+	if G.done || len(G.generators) == 0 {
+		return "", false
+	}
+	if !G.started {
+		G.started = true
+		G.current = make([]string, len(G.generators))
+		for i, generator := range G.generators {
+			generator.Reset()
+			value, ok := generator.Next()
+			if !ok {
+				G.done = true
+				return "", false
+			}
+			G.current[i] = value
+		}
+		return strings.Join(G.current, G.separator), true
+	}
+	for i := len(G.generators) - 1; i >= 0; i-- {
+		if value, ok := G.generators[i].Next(); ok {
+			G.current[i] = value
+			return strings.Join(G.current, G.separator), true
+		}
+		// restart the exhausted generator and advance the previous one
+		G.generators[i].Reset()
+		G.current[i], _ = G.generators[i].Next()
+	}
+	G.done = true
+	return "", false
+}
+
+func (G *productGenerator) Reset() {
+	G.started = false
+	G.done = false
+}
+
+func (G *productGenerator) Size() int64 {
+	if len(G.generators) == 0 {
+		return 0
+	}
+	var size int64 = 1
+	for _, generator := range G.generators {
+		n := generator.Size()
+		if n < 0 || (n > 0 && size > (1<<63-1)/n) {
+			return -1
+		}
+		size *= n
+	}
+	return size
+}
+
+// concatGenerator produces the values of generators one after the other
+type concatGenerator struct {
+	generators []Generator
+	index      int
+}
+
+// Concat returns a Generator of the values of each generator one after the other
+func Concat(generators ...Generator) Generator {
+	G := &concatGenerator{generators: generators}
+	G.Reset()
+	return G
+}
+
+func (G *concatGenerator) Next() (string, bool) {
+	for ; G.index < len(G.generators); G.index++ {
+		if value, ok := G.generators[G.index].Next(); ok {
+			return value, true
+		}
+		if G.index+1 < len(G.generators) {
+			G.generators[G.index+1].Reset()
+		}
+	}
+	return "", false
+}
+
+func (G *concatGenerator) Reset() {
+	G.index = 0
+	if len(G.generators) > 0 {
+		G.generators[0].Reset()
+	}
+}
+
+func (G *concatGenerator) Size() int64 {
+	var size int64
+	for _, generator := range G.generators {
+		n := generator.Size()
+		if n < 0 || size+n < 0 {
+			return -1
+		}
+		size += n
+	}
+	return size
+}
+
+// Loader returns the stored payloads of payload types, used by the product and concat generator specs
+type Loader func(InputTypes []string) ([]Payload, error)
+
+// generatorOptions reads the key=value options of a generator spec separated by commas
+func generatorOptions(options string) map[string]string {
+	values := make(map[string]string)
+	for _, option := range strings.Split(options, ",") {
+		parts := strings.SplitN(option, "=", 2)
+		if len(parts) == 2 {
+			values[strings.TrimSpace(parts[0])] = parts[1]
+		}
+	}
+	return values
+}
+
+// intOption returns the integer option name, fallback when it isn't set
+func intOption(options map[string]string, name string, fallback int64) (int64, error) {
+	value, ok := options[name]
+	if !ok {
+		return fallback, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %s", name, value)
+	}
+	return n, nil
+}
+
+// ParseGenerator reads a generator spec:
+//
+//	range:from=1,to=1000,step=1,width=4
+//	charset:chars=abc123,min=1,max=3
+//	dates:from=2020-01-01,to=2020-12-31,days=1,layout=20060102
+//	product:types=USERS|PASSWORDS,separator=:
+//	concat:types=XSS|SQLI
+//
+// Option values can't contain commas, product and concat read the payloads of their types with load.
+func ParseGenerator(spec string, load Loader) (Generator, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	options := map[string]string{}
+	if len(parts) == 2 {
+		options = generatorOptions(parts[1])
+	}
+	switch strings.ToLower(parts[0]) {
+	case "range":
+		var values [4]int64
+		var err error
+		for i, name := range []string{"from", "to", "step", "width"} {
+			fallback := int64(1)
+			if name == "width" {
+				fallback = 0
+			}
+			values[i], err = intOption(options, name, fallback)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if _, ok := options["to"]; !ok {
+			return nil, fmt.Errorf("range needs a to option")
+		}
+		return Range(values[0], values[1], values[2], int(values[3]))
+	case "charset":
+		min, err := intOption(options, "min", 1)
+		if err != nil {
+			return nil, err
+		}
+		max, err := intOption(options, "max", min)
+		if err != nil {
+			return nil, err
+		}
+		return Charset(options["chars"], int(min), int(max))
+	case "dates":
+		layout := options["layout"]
+		if layout == "" {
+			layout = "2006-01-02"
+		}
+		start, err := time.Parse("2006-01-02", options["from"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid dates from %s, use YYYY-MM-DD", options["from"])
+		}
+		end, err := time.Parse("2006-01-02", options["to"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid dates to %s, use YYYY-MM-DD", options["to"])
+		}
+		days, err := intOption(options, "days", 1)
+		if err != nil {
+			return nil, err
+		}
+		return Dates(start, end, int(days), layout)
+	case "product", "concat":
+		if options["types"] == "" || load == nil {
+			return nil, fmt.Errorf("%s needs payload types like types=USERS|PASSWORDS", parts[0])
+		}
+		var generators []Generator
+		for _, inputtype := range strings.Split(options["types"], "|") {
+			stored, err := load([]string{inputtype})
+			if err != nil {
+				return nil, err
+			}
+			stored, _ = Dedup(stored)
+			generators = append(generators, FromPayloads(stored))
+		}
+		if strings.ToLower(parts[0]) == "concat" {
+			return Concat(generators...), nil
+		}
+		return Product(options["separator"], generators...), nil
+	}
+	return nil, fmt.Errorf("unknown generator %s, use range, charset, dates, product or concat", parts[0])
+}
+
+// Generators maps payload types to the Generator of their payloads
+type Generators map[string]Generator
+
+// DefaultGenerateLimit is the default maximum number of payloads generated for a scan.
+// Every generated payload becomes test cases stored with their responses, so scans can't use generators lazily.
+const DefaultGenerateLimit = 100000
+
+// ParseGenerators reads generators written as TYPE=spec, see ParseGenerator.
+// It refuses generators producing more than limit payloads together, limit <= 0 doesn't limit them.
+func ParseGenerators(specs []string, load Loader, limit int64) (Generators, error) {
+	generators := make(Generators)
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("generators must look like TYPE=spec, got %s", spec)
+		}
+		generator, err := ParseGenerator(parts[1], load)
+		if err != nil {
+			return nil, err
+		}
+		generators[NormalizeType(parts[0])] = generator
+	}
+	if size := generators.Size(); limit > 0 && (size < 0 || size > limit) {
+		return nil, fmt.Errorf("the generators produce %s payloads, more than the limit of %d", sizeString(size), limit)
+	}
+	return generators, nil
+}
+
+// sizeString returns a Generator size for messages
+func sizeString(size int64) string {
+	if size < 0 {
+		return "more than 9223372036854775807"
+	}
+	return strconv.FormatInt(size, 10)
+}
+
+// Size returns the number of payloads produced by the generators, -1 when larger than an int64
+func (G Generators) Size() int64 {
+	var size int64
+	for _, generator := range G {
+		n := generator.Size()
+		if n < 0 || size+n < 0 {
+			return -1
+		}
+		size += n
+	}
+	return size
+}
+
+// Payloads returns the payloads produced by the generators, by payload type.
+// It holds every payload in memory, check Size first or iterate a single Generator with Each.
+func (G Generators) Payloads() []Payload {
+	var generated []Payload
+	types := make([]string, 0, len(G))
+	for inputtype := range G {
+		types = append(types, inputtype)
+	}
+	sort.Strings(types)
+	for _, inputtype := range types {
+		Each(G[inputtype], func(value string) bool {
+			generated = append(generated, New(inputtype, value))
+			return true
+		})
+	}
+	return generated
+}
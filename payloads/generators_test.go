@@ -0,0 +1,126 @@
+package payloads
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// values returns every value of generator
+func values(generator Generator) []string {
+	var generated []string
+	Each(generator, func(value string) bool {
+		generated = append(generated, value)
+		return true
+	})
+	return generated
+}
+
+func TestGenerators(t *testing.T) {
+	rangeUp, _ := Range(8, 12, 2, 3)
+	rangeDown, _ := Range(1, -1, 1, 0)
+	charset, _ := Charset("ab", 1, 2)
+	dates, _ := Dates(time.Date(2020, 2, 27, 0, 0, 0, 0, time.UTC), time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC), 2, "20060102")
+	users := FromPayloads([]Payload{New("USERS", "admin"), New("USERS", "root")})
+	pins, _ := Range(0, 1, 1, 2)
+	tests := []struct {
+		name      string
+		generator Generator
+		expected  []string
+	}{
+		{"range", rangeUp, []string{"008", "010", "012"}},
+		{"range down", rangeDown, []string{"1", "0", "-1"}},
+		{"charset", charset, []string{"a", "b", "aa", "ab", "ba", "bb"}},
+		{"dates", dates, []string{"20200227", "20200229", "20200302"}},
+		{"product", Product(":", users, pins), []string{"admin:00", "admin:01", "root:00", "root:01"}},
+		{"concat", Concat(List([]string{"x"}), List(nil), List([]string{"y", "z"})), []string{"x", "y", "z"}},
+		{"empty product", Product(":", users, List(nil)), nil},
+	}
+	for _, test := range tests {
+		generated := values(test.generator)
+		if !reflect.DeepEqual(generated, test.expected) {
+			t.Errorf("Expected %s values %v got %v\n", test.name, test.expected, generated)
+		}
+		if test.generator.Size() != int64(len(test.expected)) {
+			t.Errorf("Expected %s size %d got %d\n", test.name, len(test.expected), test.generator.Size())
+		}
+		if again := values(test.generator); !reflect.DeepEqual(again, generated) {
+			t.Errorf("Expected %s to start over after Reset got %v\n", test.name, again)
+		}
+	}
+}
+
+func TestGeneratorIsLazy(t *testing.T) {
+	charset, _ := Charset("abcdefghijklmnopqrstuvwxyz0123456789", 1, 13)
+	if charset.Size() != -1 {
+		t.Errorf("Expected the size of 36^13 strings to overflow got %d\n", charset.Size())
+	}
+	var generated []string
+	Each(charset, func(value string) bool {
+		generated = append(generated, value)
+		return len(generated) < 3
+	})
+	if !reflect.DeepEqual(generated, []string{"a", "b", "c"}) {
+		t.Errorf("Expected the first 3 values got %v\n", generated)
+	}
+}
+
+func TestParseGenerators(t *testing.T) {
+	load := func(InputTypes []string) ([]Payload, error) {
+		return []Payload{New(InputTypes[0], InputTypes[0]+"1"), New(InputTypes[0], InputTypes[0]+"2")}, nil
+	}
+	tests := []struct {
+		spec     string
+		expected []string
+		ok       bool
+	}{
+		{"range:from=1,to=3,width=2", []string{"01", "02", "03"}, true},
+		{"charset:chars=xy,min=2", []string{"xx", "xy", "yx", "yy"}, true},
+		{"dates:from=2020-12-31,to=2021-01-01", []string{"2020-12-31", "2021-01-01"}, true},
+		{"product:types=A|B,separator=-", []string{"A1-B1", "A1-B2", "A2-B1", "A2-B2"}, true},
+		{"concat:types=A|B", []string{"A1", "A2", "B1", "B2"}, true},
+		{"range:from=1", nil, false},
+		{"range:to=5,step=0", nil, false},
+		{"dates:from=yesterday,to=2021-01-01", nil, false},
+		{"shuffle:types=A", nil, false},
+	}
+	for _, test := range tests {
+		generator, err := ParseGenerator(test.spec, load)
+		if (err == nil) != test.ok {
+			t.Errorf("Expected %s to parse %t got %v\n", test.spec, test.ok, err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(values(generator), test.expected) {
+			t.Errorf("Expected %s values %v got %v\n", test.spec, test.expected, values(generator))
+		}
+	}
+
+	generators, err := ParseGenerators([]string{"idor=range:to=2", "PIN=charset:chars=1"}, load, 3)
+	if err != nil {
+		t.Fatalf("Error parsing generators: %s\n", err)
+	}
+	expected := []Payload{New("IDOR", "1"), New("IDOR", "2"), New("PIN", "1")}
+	if generated := generators.Payloads(); !reflect.DeepEqual(generated, expected) {
+		t.Errorf("Expected payloads %v got %v\n", expected, generated)
+	}
+	if _, err = ParseGenerators([]string{"range:to=2"}, load, 0); err == nil {
+		t.Errorf("Expected an error for a generator without a payload type\n")
+	}
+	limits := []struct {
+		specs []string
+		limit int64
+		ok    bool
+	}{
+		{[]string{"IDOR=range:to=1000", "PIN=range:to=1000"}, 2000, true},
+		{[]string{"IDOR=range:to=1000", "PIN=range:to=1000"}, 1999, false},
+		{[]string{"BRUTE=charset:chars=abcdefghijklmnopqrstuvwxyz0123456789,max=6"}, DefaultGenerateLimit, false},
+		{[]string{"BRUTE=charset:chars=abcdefghijklmnopqrstuvwxyz0123456789,max=13"}, DefaultGenerateLimit, false},
+		{[]string{"BRUTE=charset:chars=abcdefghijklmnopqrstuvwxyz0123456789,max=13"}, 0, true},
+		{[]string{"IDOR=range:from=-9223372036854775807,to=9223372036854775807"}, DefaultGenerateLimit, false},
+	}
+	for _, test := range limits {
+		if _, err = ParseGenerators(test.specs, load, test.limit); (err == nil) != test.ok {
+			t.Errorf("Expected %v under the limit %d to parse %t got %v\n", test.specs, test.limit, test.ok, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package payloads
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Metadata describes how to verify a payload and where it applies, every field is optional
+type Metadata struct {
+	ExpectedResponse string   `json:"expected_response,omitempty" bson:"expected_response,omitempty"` // Regex matching the response body when the payload worked
+	ExpectedDelay    float64  `json:"expected_delay,omitempty" bson:"expected_delay,omitempty"`       // Seconds the response is delayed when the payload worked, like 5 for SLEEP(5)
+	Context          []string `json:"context,omitempty" bson:"context,omitempty"`                     // Injection point types the payload targets like QUERY or JSON, every type when empty
+	Platform         string   `json:"platform,omitempty" bson:"platform,omitempty"`                   // DBMS or platform targeted like mysql or windows
+	Risk             string   `json:"risk,omitempty" bson:"risk,omitempty"`                           // Severity of the vulnerability the payload confirms, like HIGH
+}
+
+// Validate checks the expected response regex and the risk level of the Metadata
+func (M Metadata) Validate() error {
+	if M.ExpectedResponse != "" {
+		_, err := regexp.Compile(M.ExpectedResponse)
+		if err != nil {
+			return fmt.Errorf("invalid expected_response %s: %s", M.ExpectedResponse, err)
+		}
+	}
+	if M.ExpectedDelay < 0 {
+		return fmt.Errorf("invalid expected_delay %v", M.ExpectedDelay)
+	}
+	switch strings.ToUpper(M.Risk) {
+	case "", "INFO", "LOW", "MEDIUM", "HIGH", "CRITICAL":
+		return nil
+	}
+	return fmt.Errorf("unknown risk %s, use INFO, LOW, MEDIUM, HIGH or CRITICAL", M.Risk)
+}
+
+// IsZero reports whether no Metadata is set, empty Metadata is left out of BSON documents
+func (M Metadata) IsZero() bool {
+	return M.ExpectedResponse == "" && M.ExpectedDelay == 0 && len(M.Context) == 0 && M.Platform == "" && M.Risk == ""
+}
+
+// Delay returns the ExpectedDelay as a time.Duration
+func (M Metadata) Delay() time.Duration {
+	return time.Duration(M.ExpectedDelay * float64(time.Second))
+}
+
+// Targets reports whether the payload applies to an injection point type
+func (M Metadata) Targets(injectionPointType string) bool {
+	if len(M.Context) == 0 {
+		return true
+	}
+	for _, context := range M.Context {
+		if strings.EqualFold(context, injectionPointType) || context == AllTypes {
+			return true
+		}
+	}
+	return false
+}
+
+// ForInjectionPointType returns the payloads targeting an injection point type
+func ForInjectionPointType(payloads []Payload, injectionPointType string) []Payload {
+	var targeted []Payload
+	for _, payload := range payloads {
+		if payload.Targets(injectionPointType) {
+			targeted = append(targeted, payload)
+		}
+	}
+	return targeted
+}
+
+// ReadJSONPayloads reads payloads with their Metadata from a JSON file in the sample/injections_format.json layout or a JSON array.
+// Payloads without a type get defaultType.
+func ReadJSONPayloads(filename string, defaultType string) ([]Payload, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var file InjectionsFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		if err = json.Unmarshal(data, &file.Inputs); err != nil {
+			return nil, err
+		}
+	}
+	for i, payload := range file.Inputs {
+		if payload.InputType == "" {
+			file.Inputs[i].InputType = defaultType
+		}
+		if err = payload.Validate(); err != nil {
+			return nil, fmt.Errorf("payload %d: %s", i, err)
+		}
+	}
+	return file.Inputs, nil
+}
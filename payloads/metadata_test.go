@@ -0,0 +1,55 @@
+package payloads
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadJSONPayloads(t *testing.T) {
+	read, err := ReadJSONPayloads(filepath.Join("..", "sample", "injections_format.json"), "")
+	if err != nil {
+		t.Fatalf("Error reading sample payloads: %s\n", err)
+	}
+	if len(read) != 4 {
+		t.Fatalf("Expected 4 sample payloads got %d\n", len(read))
+	}
+	sleep := read[2]
+	if sleep.Delay() != 5*time.Second || sleep.Platform != "mysql" || sleep.Risk != "high" || len(sleep.Tags) != 2 {
+		t.Errorf("Expected the SLEEP(5) payload metadata got %+v\n", sleep)
+	}
+	if sleep.Targets("HEADER") || !sleep.Targets("query") || !read[0].Targets("HEADER") {
+		t.Errorf("Expected the SLEEP(5) payload to only target its context\n")
+	}
+	if targeted := ForInjectionPointType(read, "PATH"); len(targeted) != 3 {
+		t.Errorf("Expected 3 payloads targeting PATH got %d\n", len(targeted))
+	}
+
+	dir, err := ioutil.TempDir("", "payloads")
+	if err != nil {
+		t.Fatalf("Error creating temporary directory: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		content string
+		fails   bool
+	}{
+		{`[{"value": "a", "expected_response": "b"}]`, false},
+		{`[{"value": "a", "expected_response": "("}]`, true},
+		{`[{"value": "a", "risk": "extreme"}]`, true},
+		{`{"inputs": [{"value": "a", "expected_delay": -1}]}`, true},
+	}
+	for _, test := range tests {
+		filename := filepath.Join(dir, "payloads.json")
+		ioutil.WriteFile(filename, []byte(test.content), 0644)
+		read, err := ReadJSONPayloads(filename, "XSS")
+		if (err != nil) != test.fails {
+			t.Errorf("Expected %s to fail %t got %v\n", test.content, test.fails, err)
+		}
+		if err == nil && read[0].InputType != "XSS" {
+			t.Errorf("Expected the default type got %s\n", read[0].InputType)
+		}
+	}
+}
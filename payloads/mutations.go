@@ -0,0 +1,136 @@
+package payloads
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MutationType is the payload type of the payloads derived from the original value of an injection point
+const MutationType = "MUTATION"
+
+// Mutator derives payloads from the original value of an injection point
+type Mutator struct {
+	Name   string
+	Mutate func(original string) []string
+}
+
+// maxBitFlips is the number of bytes of the original value flipped by the bit flip Mutator
+const maxBitFlips = 8
+
+// boundaryIntegers are the limits of the usual integer sizes and their overflows
+var boundaryIntegers = []string{
+	"0", "-1", "1",
+	"127", "128", "-128", "-129", "255", "256",
+	"32767", "32768", "-32768", "-32769", "65535", "65536",
+	"2147483647", "2147483648", "-2147483648", "-2147483649", "4294967295", "4294967296",
+	"9223372036854775807", "9223372036854775808", "-9223372036854775808", "-9223372036854775809", "18446744073709551615", "18446744073709551616",
+	"1e308", "-1e308", "1e-308", "0.1", "NaN", "Infinity",
+}
+
+// longStringLengths are the lengths of the long strings of the long string Mutator
+var longStringLengths = []int{256, 1024, 4096, 65536}
+
+// formatStrings are printf style format strings reading or writing the stack
+var formatStrings = []string{"%s%s%s%s%s%s%s%s", "%x%x%x%x%x%x%x%x", "%p%p%p%p%p%p%p%p", "%n%n%n%n%n%n%n%n", "%d%d%d%d", "%99999999s", "%.1024d", "{0}{1}{2}"}
+
+// BitFlip flips a different bit of up to 8 bytes spread over the original value
+func BitFlip(original string) []string {
+	var mutations []string
+	flips := len(original)
+	if flips > maxBitFlips {
+		flips = maxBitFlips
+	}
+	for i := 0; i < flips; i++ {
+		mutated := []byte(original)
+		mutated[i*len(original)/flips] ^= 1 << uint(i%8)
+		mutations = append(mutations, string(mutated))
+	}
+	return mutations
+}
+
+// BoundaryIntegers returns the integer boundaries, and the neighbours of the original value when it is an integer
+func BoundaryIntegers(original string) []string {
+	mutations := append([]string{}, boundaryIntegers...)
+	if n, err := strconv.ParseInt(original, 10, 64); err == nil {
+		mutations = append(mutations, strconv.FormatInt(n-1, 10), strconv.FormatInt(n+1, 10), strconv.FormatInt(-n, 10))
+	}
+	return mutations
+}
+
+// LongStrings repeats the original value, or A when it is empty, to lengths from 256 to 65536 characters
+func LongStrings(original string) []string {
+	var mutations []string
+	if original == "" {
+		original = "A"
+	}
+	for _, length := range longStringLengths {
+		mutations = append(mutations, strings.Repeat(original, length/len(original)+1)[:length])
+	}
+	return mutations
+}
+
+// FormatStrings returns format strings alone and appended to the original value
+func FormatStrings(original string) []string {
+	var mutations []string
+	for _, format := range formatStrings {
+		mutations = append(mutations, format, original+format)
+	}
+	return mutations
+}
+
+// UnicodeEdgeCases surrounds the original value with NUL bytes, invalid and overlong UTF-8, surrogates, byte order marks,
+// bidirectional overrides, zero width and combining characters
+func UnicodeEdgeCases(original string) []string {
+	return []string{
+		original + "\x00",
+		"\x00" + original,
+		original + "\xff\xfe",
+		original + "\xc0\xaf",
+		original + "\xed\xa0\x80",
+		"\ufeff" + original,
+		"\u202e" + original,
+		original + "\u200b\u200d\u2060",
+		original + strings.Repeat("\u0301", 64),
+		original + "\U0001F4A9",
+		strings.ToUpper(original) + "\u0130\u0131",
+		"\uff1c\uff1e" + original,
+	}
+}
+
+// Deletion replaces the original value with an empty value
+func Deletion(original string) []string {
+	return []string{""}
+}
+
+// DefaultMutators are the Mutators used by Mutate when none are given
+var DefaultMutators = []Mutator{
+	{"bit-flip", BitFlip},
+	{"boundary-integer", BoundaryIntegers},
+	{"long-string", LongStrings},
+	{"format-string", FormatStrings},
+	{"unicode", UnicodeEdgeCases},
+	{"deletion", Deletion},
+}
+
+// Mutate returns the MUTATION payloads derived from the original value of the injection point by mutators, DefaultMutators when empty.
+// The Description of the payloads is the name of their Mutator, mutations equal to the original value or to an earlier mutation are left out.
+func Mutate(point string, original string, mutators []Mutator) []Payload {
+	if len(mutators) == 0 {
+		mutators = DefaultMutators
+	}
+	var mutations []Payload
+	seen := map[string]bool{original: true}
+	for _, mutator := range mutators {
+		for _, value := range mutator.Mutate(original) {
+			if seen[value] {
+				continue
+			}
+			seen[value] = true
+			mutation := New(MutationType, value)
+			mutation.Description = mutator.Name
+			mutation.Point = point
+			mutations = append(mutations, mutation)
+		}
+	}
+	return mutations
+}
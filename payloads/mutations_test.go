@@ -0,0 +1,54 @@
+package payloads
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMutators(t *testing.T) {
+	flips := BitFlip("ab")
+	if len(flips) != 2 || flips[0] != "`b" || flips[1] != "a`" {
+		t.Errorf("Expected a bit flipped in each byte got %q\n", flips)
+	}
+	if len(BitFlip(strings.Repeat("a", 100))) != maxBitFlips {
+		t.Errorf("Expected at most %d bit flips\n", maxBitFlips)
+	}
+	integers := BoundaryIntegers("41")
+	if !strings.Contains(strings.Join(integers, " "), "2147483648 ") || integers[len(integers)-3] != "40" || integers[len(integers)-2] != "42" {
+		t.Errorf("Expected the integer boundaries and the neighbours of 41 got %v\n", integers)
+	}
+	long := LongStrings("abc")
+	if len(long[0]) != 256 || !strings.HasPrefix(long[0], "abcabc") || len(long[len(long)-1]) != 65536 {
+		t.Errorf("Expected the original value repeated to 256 and 65536 characters got %d %d\n", len(long[0]), len(long[len(long)-1]))
+	}
+	if LongStrings("")[0] != strings.Repeat("A", 256) {
+		t.Errorf("Expected A repeated for an empty value\n")
+	}
+}
+
+func TestMutate(t *testing.T) {
+	mutations := Mutate("id", "1", nil)
+	descriptions := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, mutation := range mutations {
+		if mutation.InputType != MutationType || mutation.Point != "id" {
+			t.Errorf("Expected a MUTATION payload of id got %+v\n", mutation)
+		}
+		if mutation.Value == "1" || seen[mutation.Value] {
+			t.Errorf("Expected mutations without the original value and duplicates got %q\n", mutation.Value)
+		}
+		seen[mutation.Value] = true
+		descriptions[mutation.Description] = true
+	}
+	for _, mutator := range DefaultMutators {
+		if !descriptions[mutator.Name] {
+			t.Errorf("Expected mutations of %s\n", mutator.Name)
+		}
+	}
+	if !seen[""] || !seen["2"] || !seen["%n%n%n%n%n%n%n%n"] || !seen["1\x00"] {
+		t.Errorf("Expected the deleted value, 2, format strings and NUL bytes\n")
+	}
+	if !mutations[0].Injects("id") || mutations[0].Injects("name") || !New("XSS", "x").Injects("name") {
+		t.Errorf("Expected mutations to only be injected in their injection point\n")
+	}
+}
@@ -2,13 +2,11 @@ package payloads
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
+	"path/filepath"
 	"strings"
-	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -17,8 +15,20 @@ import (
 
 // Payload represents a fuzzer input
 type Payload struct {
-	InputType string `json:"type"`
-	Value     string `json:"value"`
+	ID          string   `json:"-" bson:"-"` // Identifier of the payload in its Store
+	InputType   string   `json:"type" bson:"type"`
+	Value       string   `json:"value" bson:"value"`
+	Tags        []string `json:"tags,omitempty" bson:"tags,omitempty"`
+	Description string   `json:"description,omitempty" bson:"description,omitempty"`
+	Original    string   `json:"original,omitempty" bson:"original,omitempty"` // Value before the Pipeline was applied
+	Pipeline    string   `json:"pipeline,omitempty" bson:"pipeline,omitempty"` // Processors applied to Original, see Pipeline
+	Point       string   `json:"-" bson:"-"`                                   // Injection point the payload was derived from, every injection point when empty
+	Metadata    `bson:",inline"`
+}
+
+// Injects reports whether the payload is injected in the injection point
+func (P Payload) Injects(point string) bool {
+	return P.Point == "" || P.Point == point
 }
 
 // New take payload type and value returns a Payload
@@ -29,73 +39,59 @@ func New(inputtype string, value string) Payload {
 	}
 }
 
-// CreatePayloadsFromInputTypes takes an array of Payload InputTypes and an mongodb uri and returns an array of Payloads of that type from mongodb
+// CreatePayloadsFromInputTypes takes an array of Payload InputTypes and an mongodb uri and returns an array of Payloads of that type from mongodb without blank values and duplicates
 func CreatePayloadsFromInputTypes(InputTypes []string, mongodbURI string) ([]Payload, error) {
 	var payloads []Payload
-	var temppayloads []Payload
-	client, err := mongo.NewClient(options.Client().ApplyURI(mongodbURI))
+	store, err := NewMongoStore(mongodbURI, "pandushi")
 	if err != nil {
-		return payloads, err
+		return payloads, fmt.Errorf("payloads.CreatePayloadsFromInputTypes mongodb connection error: %s", err)
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	err = client.Connect(ctx)
+	defer store.Close()
+
+	payloads, err = store.Load(InputTypes)
 	if err != nil {
-		log.Fatalln(err)
+		return payloads, err
 	}
-	err = client.Ping(ctx, nil)
-	if err != nil {
-		log.Fatalf("payloads.CreatePayloadsFromInputTypes mongodb connection error: %s\n", err)
-	}
-	defer client.Disconnect(ctx)
-	defer cancel()
-
-	for _, inputtype := range InputTypes {
-
-		filter := bson.M{"type": strings.ToUpper(inputtype)}
-		pandushiDB := client.Database("pandushi")
-		injectionsCollection := pandushiDB.Collection("injections")
-
-		cursor, err := injectionsCollection.Find(ctx, filter)
-		if err != nil {
-			continue
-		}
-
-		if err = cursor.All(ctx, &temppayloads); err != nil {
-			continue
-		} else {
-			payloads = append(payloads, temppayloads...)
-		}
-
+	payloads, skipped := Dedup(payloads)
+	if skipped.Total() > 0 {
+		fmt.Printf("Skipped %d duplicate and %d blank payloads\n", skipped.Duplicates, skipped.Blank)
 	}
 	return payloads, nil
 }
 
+// EnsureIndexes creates the unique type and value index of the injections collection
+func EnsureIndexes(ctx context.Context, injectionsCollection *mongo.Collection) error {
+	_, err := injectionsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "type", Value: 1}, {Key: "value", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("type_value"),
+	})
+	return err
+}
+
 // PayloadFromFileByInputTypes takes an array of Payload InputTypes and a file uri and returns an array of Payload of that type from the payloads included in the file
 /*
 func PayloadFromFileByInputTypes(InputTypes []string, fileURI string) ([]Payload, error) {
 }
 */
 
-// NewPayloadsFromFileToMongoDB creates and stores payload to mongodb
+// NewPayloadsFromFileToMongoDB creates and stores payload to mongodb, blank lines and payloads already stored are skipped
 func NewPayloadsFromFileToMongoDB(payloadType string, InputFname string, mongodbURI string, dbName string) ([]Payload, error) {
-	var testPayloads []Payload
-	injectionsCount := 0
-
-	client, err := mongo.NewClient(options.Client().ApplyURI(mongodbURI))
+	store, err := NewMongoStore(mongodbURI, dbName)
 	if err != nil {
-		return testPayloads, err
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	err = client.Connect(ctx)
-	if err != nil {
-		cancel()
-		return testPayloads, err
+		return nil, err
 	}
-	defer client.Disconnect(ctx)
-	defer cancel()
+	defer store.Close()
+	return newPayloadsFromFile(payloadType, InputFname, store)
+}
+
+// NewPayloadsFromFileToJSONFile creates and stores payload to a json file, blank lines and payloads already in the file are skipped
+func NewPayloadsFromFileToJSONFile(payloadType string, InputFname string, outFilename string) ([]Payload, error) {
+	return newPayloadsFromFile(payloadType, InputFname, &FileStore{Filename: outFilename})
+}
 
-	pandushiDB := client.Database(dbName)
-	injectionsCollection := pandushiDB.Collection("injections")
+// newPayloadsFromFile stores the payloads of a file with one payload per line, or of a JSON file read by ReadJSONPayloads, into store
+func newPayloadsFromFile(payloadType string, InputFname string, store Store) ([]Payload, error) {
+	var testPayloads []Payload
 
 	payloadfd, err := os.Open(InputFname)
 	if err != nil {
@@ -111,65 +107,23 @@ func NewPayloadsFromFileToMongoDB(payloadType string, InputFname string, mongodb
 	fmt.Printf("PayloadRAW: %s\n", payloadsRaw)
 	fmt.Printf("PayloadType: %s\n", payloadType)
 
-	for _, line := range strings.Split(string(payloadsRaw), "\n") {
-		testPayloads = append(testPayloads,
-			Payload{
-				InputType: strings.ToUpper(payloadType),
-				Value:     line,
-			})
-		injectionsResult, err := injectionsCollection.InsertOne(ctx, bson.D{
-			{Key: "type", Value: payloadType},
-			{Key: "value", Value: line},
-		})
+	var skipped Skipped
+	if strings.EqualFold(filepath.Ext(InputFname), ".json") {
+		testPayloads, err = ReadJSONPayloads(InputFname, payloadType)
 		if err != nil {
 			return testPayloads, err
-		} else {
-			injectionsCount++
-			fmt.Printf("inserted document with ID %v\n", injectionsResult.InsertedID)
 		}
+		testPayloads, skipped = Dedup(testPayloads)
+	} else {
+		testPayloads, skipped = ParseLines(payloadType, string(payloadsRaw))
 	}
-	fmt.Printf("Inserted %v documents into injections collection!\n", injectionsCount)
-
-	return testPayloads, nil
-}
-
-// NewPayloadsFromFileToJSONFile creates and stores payload to a json file
-func NewPayloadsFromFileToJSONFile(payloadType string, InputFname string, outFilename string) ([]Payload, error) {
-	var testPayloads []Payload
-	injectionsCount := 0
-
-	payloadfd, err := os.Open(InputFname)
+	injectionsCount, duplicates, err := store.Add(testPayloads)
 	if err != nil {
 		return testPayloads, err
 	}
-	defer payloadfd.Close()
-
-	payloadsRaw, err := ioutil.ReadAll(payloadfd)
-	if err != nil {
-		return testPayloads, err
-	}
-
-	fmt.Printf("PayloadRAW: %s\n", payloadsRaw)
-	fmt.Printf("PayloadType: %s\n", payloadType)
-
-	for _, line := range strings.Split(string(payloadsRaw), "\n") {
-		testPayloads = append(testPayloads,
-			Payload{
-				InputType: strings.ToUpper(payloadType),
-				Value:     line,
-			})
-		injectionsCount++
-	}
+	skipped.Duplicates += duplicates.Duplicates
 	fmt.Printf("Inserted %v documents into injections collection!\n", injectionsCount)
-	outfd, err := os.OpenFile(outFilename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		panic(err)
-	}
-	defer outfd.Close()
-	enc := json.NewEncoder(outfd)
-	enc.SetEscapeHTML(false)
-	enc.SetIndent("", "    ")
-	enc.Encode(testPayloads)
+	fmt.Printf("Skipped %d duplicate payloads and %d blank lines\n", skipped.Duplicates, skipped.Blank)
 
 	return testPayloads, nil
 }
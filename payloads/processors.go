@@ -0,0 +1,185 @@
+package payloads
+
+import (
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// Processor transforms the value of a Payload
+type Processor func(value string) string
+
+// Processors are the processors available to a Pipeline by name.
+// The prefix:<text> and suffix:<text> processors take their text after the colon.
+var Processors = map[string]Processor{
+	"url":               url.QueryEscape,
+	"double-url":        func(value string) string { return url.QueryEscape(url.QueryEscape(value)) },
+	"base64":            func(value string) string { return base64.StdEncoding.EncodeToString([]byte(value)) },
+	"html-entity":       htmlEntityEncode,
+	"unicode-escape":    unicodeEscape,
+	"hex":               hexEscape,
+	"random-case":       randomCase,
+	"sql-comment-space": func(value string) string { return strings.Replace(value, " ", "/**/", -1) },
+}
+
+// htmlEntityEncode encodes every character of value as a decimal HTML entity
+func htmlEntityEncode(value string) string {
+	var encoded strings.Builder
+	for _, r := range value {
+		fmt.Fprintf(&encoded, "&#%d;", r)
+	}
+	return encoded.String()
+}
+
+// unicodeEscape encodes every character of value as a \uXXXX escape, using surrogate pairs outside of the BMP
+func unicodeEscape(value string) string {
+	var encoded strings.Builder
+	for _, unit := range utf16.Encode([]rune(value)) {
+		fmt.Fprintf(&encoded, "\\u%04x", unit)
+	}
+	return encoded.String()
+}
+
+// hexEscape encodes every byte of value as a \xXX escape
+func hexEscape(value string) string {
+	var encoded strings.Builder
+	for i := 0; i < len(value); i++ {
+		fmt.Fprintf(&encoded, "\\x%02x", value[i])
+	}
+	return encoded.String()
+}
+
+// randomCase randomly changes the case of the letters of value.
+// The randomness is seeded with a hash of value so a recorded pipeline gives the same result when it is applied again.
+func randomCase(value string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(value))
+	random := rand.New(rand.NewSource(int64(hash.Sum64())))
+	runes := []rune(value)
+	for i, r := range runes {
+		if random.Intn(2) == 0 {
+			runes[i] = unicode.ToUpper(r)
+		} else {
+			runes[i] = unicode.ToLower(r)
+		}
+	}
+	return string(runes)
+}
+
+// ProcessorNames returns the names of the available processors
+func ProcessorNames() []string {
+	names := []string{"prefix:<text>", "suffix:<text>"}
+	for name := range Processors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// pipelineSeparator separates the steps of a Pipeline written as a string
+const pipelineSeparator = "|"
+
+// Pipeline is a chain of processors applied in order, like url|base64 or prefix:'|sql-comment-space
+type Pipeline []string
+
+// ParsePipeline reads a Pipeline written as steps separated by |
+func ParsePipeline(spec string) (Pipeline, error) {
+	var pipeline Pipeline
+	if spec == "" {
+		return pipeline, nil
+	}
+	pipeline = strings.Split(spec, pipelineSeparator)
+	return pipeline, pipeline.Validate()
+}
+
+// Validate checks that every step of the Pipeline is a known processor
+func (P Pipeline) Validate() error {
+	for _, step := range P {
+		if strings.HasPrefix(step, "prefix:") || strings.HasPrefix(step, "suffix:") {
+			continue
+		}
+		if _, ok := Processors[step]; !ok {
+			return fmt.Errorf("unknown payload processor %s, available processors are %s", step, strings.Join(ProcessorNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// Apply runs the processors of the Pipeline on value
+func (P Pipeline) Apply(value string) string {
+	for _, step := range P {
+		switch {
+		case strings.HasPrefix(step, "prefix:"):
+			value = strings.TrimPrefix(step, "prefix:") + value
+		case strings.HasPrefix(step, "suffix:"):
+			value += strings.TrimPrefix(step, "suffix:")
+		default:
+			if processor, ok := Processors[step]; ok {
+				value = processor(value)
+			}
+		}
+	}
+	return value
+}
+
+// String returns the Pipeline written as steps separated by |
+func (P Pipeline) String() string {
+	return strings.Join(P, pipelineSeparator)
+}
+
+// AllTypes is the key of the Pipelines applied to payload types without their own Pipeline
+const AllTypes = "*"
+
+// Pipelines maps payload types to the Pipeline processing their values
+type Pipelines map[string]Pipeline
+
+// ParsePipelines reads pipelines written as TYPE=steps for a payload type or as steps for every type
+func ParsePipelines(specs []string) (Pipelines, error) {
+	pipelines := make(Pipelines)
+	for _, spec := range specs {
+		inputType := AllTypes
+		if i := strings.Index(spec, "="); i > 0 && !strings.ContainsAny(spec[:i], pipelineSeparator+":") {
+			inputType, spec = strings.ToUpper(spec[:i]), spec[i+1:]
+		}
+		pipeline, err := ParsePipeline(spec)
+		if err != nil {
+			return nil, err
+		}
+		pipelines[inputType] = pipeline
+	}
+	return pipelines, nil
+}
+
+// For returns the Pipeline of a payload type
+func (P Pipelines) For(inputType string) Pipeline {
+	if pipeline, ok := P[strings.ToUpper(inputType)]; ok {
+		return pipeline
+	}
+	return P[AllTypes]
+}
+
+// Apply returns payloads with their values processed by the Pipeline of their type.
+// The processed payloads keep their Original value and the Pipeline applied.
+func (P Pipelines) Apply(payloads []Payload) []Payload {
+	if len(P) == 0 {
+		return payloads
+	}
+	processed := make([]Payload, len(payloads))
+	for i, payload := range payloads {
+		processed[i] = payload
+		pipeline := P.For(payload.InputType)
+		if len(pipeline) == 0 {
+			continue
+		}
+		processed[i].Original = payload.Value
+		processed[i].Pipeline = pipeline.String()
+		processed[i].Value = pipeline.Apply(payload.Value)
+	}
+	return processed
+}
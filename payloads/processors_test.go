@@ -0,0 +1,66 @@
+package payloads
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPipelineApply(t *testing.T) {
+	tests := []struct {
+		spec     string
+		value    string
+		expected string
+	}{
+		{"url", "<a b>", "%3Ca+b%3E"},
+		{"double-url", "<a>", "%253Ca%253E"},
+		{"base64", "<a>", "PGE+"},
+		{"html-entity", "<a>", "&#60;&#97;&#62;"},
+		{"unicode-escape", "<é😀", "\\u003c\\u00e9\\ud83d\\ude00"},
+		{"hex", "<a", "\\x3c\\x61"},
+		{"sql-comment-space", "' or 1=1 --", "'/**/or/**/1=1/**/--"},
+		{"prefix:\">|suffix://", "x", "\">x//"},
+		{"sql-comment-space|url", "a b", "a%2F%2A%2A%2Fb"},
+		{"", "<a>", "<a>"},
+	}
+	for _, test := range tests {
+		pipeline, err := ParsePipeline(test.spec)
+		if err != nil {
+			t.Fatalf("Error parsing pipeline %s: %s\n", test.spec, err)
+		}
+		if got := pipeline.Apply(test.value); got != test.expected {
+			t.Errorf("Expected %q for %s got %q\n", test.expected, test.spec, got)
+		}
+		if pipeline.String() != test.spec {
+			t.Errorf("Expected the pipeline to be written as %s got %s\n", test.spec, pipeline)
+		}
+	}
+
+	got := Processors["random-case"]("select")
+	if !strings.EqualFold(got, "select") {
+		t.Errorf("Expected a case variation of select got %s\n", got)
+	}
+	if again := Processors["random-case"]("select"); again != got {
+		t.Errorf("Expected random-case to give the same variation of a value got %s and %s\n", got, again)
+	}
+	if _, err := ParsePipeline("url|rot13"); err == nil {
+		t.Errorf("Expected an error for an unknown processor\n")
+	}
+}
+
+func TestPipelines(t *testing.T) {
+	pipelines, err := ParsePipelines([]string{"url", "xss=html-entity|prefix:=", "prefix:a=b"})
+	if err != nil {
+		t.Fatalf("Error parsing pipelines: %s\n", err)
+	}
+	processed := pipelines.Apply([]Payload{New("XSS", "<"), New("SQLI", "' or")})
+	expected := []Payload{
+		{InputType: "XSS", Value: "=&#60;", Original: "<", Pipeline: "html-entity|prefix:="},
+		{InputType: "SQLI", Value: "a=b' or", Original: "' or", Pipeline: "prefix:a=b"},
+	}
+	for i, e := range expected {
+		if !reflect.DeepEqual(processed[i], e) {
+			t.Errorf("Expected %+v got %+v\n", e, processed[i])
+		}
+	}
+}
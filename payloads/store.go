@@ -0,0 +1,433 @@
+package payloads
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultStoreURI is the payload store used by scans
+const DefaultStoreURI = "mongodb://localhost:27017"
+
+// ErrNotFound is returned for payload IDs missing from a Store
+var ErrNotFound = errors.New("payload not found")
+
+// Store is a payload storage backend
+type Store interface {
+	Load(InputTypes []string) ([]Payload, error)              // Payloads of the types, every payload when InputTypes is empty
+	Add(payloads []Payload) (int, Skipped, error)             // Stores the payloads without blank values and duplicates, returns the number inserted
+	Types() (map[string]int, error)                           // Number of payloads of each type
+	Search(inputtype string, query string) ([]Payload, error) // Payloads containing query in their value, of every type when inputtype is empty
+	Tag(id string, tags []string, description string) error   // Adds tags to a payload and sets its description when not empty
+	Delete(id string) error                                   // Deletes a payload
+	DeleteType(inputtype string) (int, error)                 // Deletes the payloads of a type and returns their number
+	Close() error
+}
+
+// OpenStore opens the payload store of a file:// or mongodb:// URI
+func OpenStore(storeURI string) (Store, error) {
+	switch {
+	case strings.HasPrefix(storeURI, "file://"):
+		return &FileStore{Filename: strings.TrimPrefix(storeURI, "file://")}, nil
+	case strings.HasPrefix(storeURI, "mongodb://"):
+		return NewMongoStore(storeURI, "pandushi")
+	}
+	return nil, fmt.Errorf("unsupported payload storage %s, use file:// or mongodb://", storeURI)
+}
+
+// InjectionsFile is the layout of sample/injections_format.json
+type InjectionsFile struct {
+	Inputs []Payload `json:"inputs"`
+}
+
+// Export writes payloads in the InjectionsFile layout
+func Export(w io.Writer, payloads []Payload) error {
+	if payloads == nil {
+		payloads = []Payload{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	return enc.Encode(InjectionsFile{Inputs: payloads})
+}
+
+// addTags returns tags with the new tags that it doesn't contain yet
+func addTags(tags []string, newTags []string) []string {
+	for _, tag := range newTags {
+		found := false
+		for _, t := range tags {
+			found = found || t == tag
+		}
+		if !found && tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// FileStore stores payloads in a JSON file
+type FileStore struct {
+	Filename string
+}
+
+// fileID identifies a payload of a FileStore by the hash of its Key
+func fileID(payload Payload) string {
+	sum := sha1.Sum([]byte(payload.Key()))
+	return hex.EncodeToString(sum[:6])
+}
+
+// read returns the payloads of the file with their IDs
+func (S *FileStore) read() ([]Payload, error) {
+	stored, err := readJSONFile(S.Filename)
+	if err != nil {
+		return stored, err
+	}
+	stored, _ = Dedup(stored)
+	for i := range stored {
+		stored[i].ID = fileID(stored[i])
+	}
+	return stored, nil
+}
+
+// write replaces the payloads of the file
+func (S *FileStore) write(payloads []Payload) error {
+	outfd, err := os.OpenFile(S.Filename, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer outfd.Close()
+	enc := json.NewEncoder(outfd)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	return enc.Encode(payloads)
+}
+
+// Load returns the payloads of the types, every payload when InputTypes is empty
+func (S *FileStore) Load(InputTypes []string) ([]Payload, error) {
+	stored, err := S.read()
+	if err != nil || len(InputTypes) == 0 {
+		return stored, err
+	}
+	var payloads []Payload
+	for _, payload := range stored {
+		for _, inputtype := range InputTypes {
+			if payload.InputType == NormalizeType(inputtype) {
+				payloads = append(payloads, payload)
+				break
+			}
+		}
+	}
+	return payloads, nil
+}
+
+// Add stores the payloads without blank values and payloads already in the file
+func (S *FileStore) Add(payloads []Payload) (int, Skipped, error) {
+	payloads, skipped := Dedup(payloads)
+	stored, err := S.read()
+	if err != nil {
+		return 0, skipped, err
+	}
+	all, duplicates := Dedup(append(stored, payloads...))
+	skipped.Duplicates += duplicates.Duplicates
+	return len(all) - len(stored), skipped, S.write(all)
+}
+
+// Types returns the number of payloads of each type
+func (S *FileStore) Types() (map[string]int, error) {
+	types := make(map[string]int)
+	stored, err := S.read()
+	for _, payload := range stored {
+		types[payload.InputType]++
+	}
+	return types, err
+}
+
+// Search returns the payloads containing query in their value, of every type when inputtype is empty
+func (S *FileStore) Search(inputtype string, query string) ([]Payload, error) {
+	var payloads []Payload
+	stored, err := S.Load(typeList(inputtype))
+	for _, payload := range stored {
+		if strings.Contains(strings.ToLower(payload.Value), strings.ToLower(query)) {
+			payloads = append(payloads, payload)
+		}
+	}
+	return payloads, err
+}
+
+// Tag adds tags to a payload and sets its description when not empty
+func (S *FileStore) Tag(id string, tags []string, description string) error {
+	stored, err := S.read()
+	if err != nil {
+		return err
+	}
+	for i := range stored {
+		if stored[i].ID == id {
+			stored[i].Tags = addTags(stored[i].Tags, tags)
+			if description != "" {
+				stored[i].Description = description
+			}
+			return S.write(stored)
+		}
+	}
+	return ErrNotFound
+}
+
+// Delete deletes a payload
+func (S *FileStore) Delete(id string) error {
+	stored, err := S.read()
+	if err != nil {
+		return err
+	}
+	for i := range stored {
+		if stored[i].ID == id {
+			return S.write(append(stored[:i], stored[i+1:]...))
+		}
+	}
+	return ErrNotFound
+}
+
+// DeleteType deletes the payloads of a type and returns their number
+func (S *FileStore) DeleteType(inputtype string) (int, error) {
+	stored, err := S.read()
+	if err != nil {
+		return 0, err
+	}
+	kept := []Payload{}
+	for _, payload := range stored {
+		if payload.InputType != NormalizeType(inputtype) {
+			kept = append(kept, payload)
+		}
+	}
+	return len(stored) - len(kept), S.write(kept)
+}
+
+// Close does nothing, the file is only open during each operation
+func (S *FileStore) Close() error {
+	return nil
+}
+
+// typeList returns inputtype as a list of types, empty when inputtype is empty
+func typeList(inputtype string) []string {
+	if inputtype == "" {
+		return nil
+	}
+	return []string{inputtype}
+}
+
+// storeTimeout limits each MongoStore operation
+const storeTimeout = 5 * time.Minute
+
+// MongoStore stores payloads in the injections collection of a mongodb database
+type MongoStore struct {
+	client     *mongo.Client
+	collection *mongo.Collection
+}
+
+// mongoPayload is a Payload with its mongodb document ID
+type mongoPayload struct {
+	ObjectID primitive.ObjectID `bson:"_id"`
+	Payload  `bson:",inline"`
+}
+
+// NewMongoStore connects to the injections collection of the dbName database
+func NewMongoStore(mongodbURI string, dbName string) (*MongoStore, error) {
+	client, err := mongo.NewClient(options.Client().ApplyURI(mongodbURI))
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = client.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+	return &MongoStore{client: client, collection: client.Database(dbName).Collection("injections")}, nil
+}
+
+// find returns the payloads matching filter with their IDs
+func (S *MongoStore) find(filter interface{}) ([]Payload, error) {
+	var payloads []Payload
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	cursor, err := S.collection.Find(ctx, filter)
+	if err != nil {
+		return payloads, err
+	}
+	var documents []mongoPayload
+	err = cursor.All(ctx, &documents)
+	for _, document := range documents {
+		document.Payload.ID = document.ObjectID.Hex()
+		payloads = append(payloads, document.Payload)
+	}
+	return payloads, err
+}
+
+// Load returns the payloads of the types, every payload when InputTypes is empty
+func (S *MongoStore) Load(InputTypes []string) ([]Payload, error) {
+	if len(InputTypes) == 0 {
+		return S.find(bson.M{})
+	}
+	var types bson.A
+	for _, inputtype := range InputTypes {
+		types = append(types, NormalizeType(inputtype))
+	}
+	return S.find(bson.M{"type": bson.M{"$in": types}})
+}
+
+// Add stores the payloads without blank values and payloads already in the collection.
+// A unique type and value index is created first, see EnsureIndexes.
+func (S *MongoStore) Add(payloads []Payload) (int, Skipped, error) {
+	payloads, skipped := Dedup(payloads)
+	if len(payloads) == 0 {
+		return 0, skipped, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	err := EnsureIndexes(ctx, S.collection)
+	if err != nil {
+		// the index can't be built over existing duplicates, the upserts below still skip them
+		fmt.Printf("payloads.MongoStore.Add index error: %s\n", err)
+	}
+	models := make([]mongo.WriteModel, len(payloads))
+	for i, payload := range payloads {
+		filter := bson.D{
+			{Key: "type", Value: payload.InputType},
+			{Key: "value", Value: payload.Value},
+		}
+		models[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.D{{Key: "$setOnInsert", Value: payload}}).SetUpsert(true)
+	}
+	result, err := S.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, skipped, err
+	}
+	inserted := int(result.UpsertedCount)
+	skipped.Duplicates += len(payloads) - inserted
+	return inserted, skipped, nil
+}
+
+// Types returns the number of payloads of each type
+func (S *MongoStore) Types() (map[string]int, error) {
+	types := make(map[string]int)
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	cursor, err := S.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$type"}, {Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}}},
+	})
+	if err != nil {
+		return types, err
+	}
+	var counts []struct {
+		Type  string `bson:"_id"`
+		Count int    `bson:"count"`
+	}
+	err = cursor.All(ctx, &counts)
+	for _, count := range counts {
+		types[count.Type] = count.Count
+	}
+	return types, err
+}
+
+// Search returns the payloads containing query in their value, of every type when inputtype is empty
+func (S *MongoStore) Search(inputtype string, query string) ([]Payload, error) {
+	filter := bson.M{"value": primitive.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}}
+	if inputtype != "" {
+		filter["type"] = NormalizeType(inputtype)
+	}
+	return S.find(filter)
+}
+
+// objectID reads the ObjectID of a payload ID
+func objectID(id string) (primitive.ObjectID, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return objectID, fmt.Errorf("invalid payload ID %s: %s", id, err)
+	}
+	return objectID, nil
+}
+
+// Tag adds tags to a payload and sets its description when not empty
+func (S *MongoStore) Tag(id string, tags []string, description string) error {
+	oid, err := objectID(id)
+	if err != nil {
+		return err
+	}
+	update := bson.M{}
+	if len(tags) > 0 {
+		update["$addToSet"] = bson.M{"tags": bson.M{"$each": tags}}
+	}
+	if description != "" {
+		update["$set"] = bson.M{"description": description}
+	}
+	if len(update) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	result, err := S.collection.UpdateOne(ctx, bson.M{"_id": oid}, update)
+	if err == nil && result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+// Delete deletes a payload
+func (S *MongoStore) Delete(id string) error {
+	oid, err := objectID(id)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	result, err := S.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err == nil && result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+// DeleteType deletes the payloads of a type and returns their number
+func (S *MongoStore) DeleteType(inputtype string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	result, err := S.collection.DeleteMany(ctx, bson.M{"type": NormalizeType(inputtype)})
+	if err != nil {
+		return 0, err
+	}
+	return int(result.DeletedCount), nil
+}
+
+// Close disconnects from mongodb
+func (S *MongoStore) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return S.client.Disconnect(ctx)
+}
+
+// SortedTypes returns the types of a Types count sorted by name
+func SortedTypes(types map[string]int) []string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
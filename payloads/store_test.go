@@ -0,0 +1,74 @@
+package payloads
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "payloads")
+	if err != nil {
+		t.Fatalf("Error creating temporary directory: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := OpenStore("file://" + filepath.Join(dir, "payloads.json"))
+	if err != nil {
+		t.Fatalf("Error opening store: %s\n", err)
+	}
+
+	inserted, skipped, err := store.Add([]Payload{New("xss", "<script>"), New("xss", "<img src=x>"), New("sqli", "' or 1=1--"), New("xss", "<script>"), New("xss", "")})
+	if err != nil || inserted != 3 || skipped.Duplicates != 1 || skipped.Blank != 1 {
+		t.Fatalf("Expected 3 payloads inserted with 1 duplicate and 1 blank got %d %+v (%v)\n", inserted, skipped, err)
+	}
+	inserted, skipped, _ = store.Add([]Payload{New("XSS", "<script>"), New("XSS", "<svg>")})
+	if inserted != 1 || skipped.Duplicates != 1 {
+		t.Errorf("Expected 1 payload inserted with 1 duplicate got %d %+v\n", inserted, skipped)
+	}
+
+	types, err := store.Types()
+	if err != nil || types["XSS"] != 3 || types["SQLI"] != 1 || len(SortedTypes(types)) != 2 {
+		t.Errorf("Expected 3 XSS and 1 SQLI payloads got %v (%v)\n", types, err)
+	}
+
+	found, err := store.Search("xss", "SCRIPT")
+	if err != nil || len(found) != 1 || found[0].Value != "<script>" || found[0].ID == "" {
+		t.Fatalf("Expected to find <script> with its ID got %+v (%v)\n", found, err)
+	}
+	err = store.Tag(found[0].ID, []string{"reflected", "basic"}, "Script tag")
+	if err != nil {
+		t.Errorf("Error tagging payload: %s\n", err)
+	}
+	store.Tag(found[0].ID, []string{"basic", "html"}, "")
+	tagged, _ := store.Search("", "<script>")
+	if len(tagged) != 1 || len(tagged[0].Tags) != 3 || tagged[0].Description != "Script tag" {
+		t.Errorf("Expected 3 tags and a description got %+v\n", tagged)
+	}
+	if err := store.Tag("missing", []string{"x"}, ""); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound tagging a missing payload got %v\n", err)
+	}
+
+	var exported bytes.Buffer
+	xss, _ := store.Load([]string{"xss"})
+	err = Export(&exported, xss)
+	var file InjectionsFile
+	if err != nil || json.Unmarshal(exported.Bytes(), &file) != nil || len(file.Inputs) != 3 || bytes.Contains(exported.Bytes(), []byte(`"id"`)) {
+		t.Errorf("Expected 3 exported inputs without IDs got %s (%v)\n", exported.String(), err)
+	}
+
+	err = store.Delete(found[0].ID)
+	if err != nil {
+		t.Errorf("Error deleting payload: %s\n", err)
+	}
+	deleted, err := store.DeleteType("XSS")
+	if err != nil || deleted != 2 {
+		t.Errorf("Expected 2 XSS payloads deleted got %d (%v)\n", deleted, err)
+	}
+	remaining, _ := store.Load(nil)
+	if len(remaining) != 1 || remaining[0].InputType != "SQLI" {
+		t.Errorf("Expected the SQLI payload to remain got %+v\n", remaining)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+
+	"github.com/gi0cann/pandushi/fuzzer"
+	"github.com/gi0cann/pandushi/payloads"
+	"github.com/gi0cann/pandushi/proxy"
+)
+
+// proxyOptions are the options of the proxy command
+type proxyOptions struct {
+	Listen      string
+	Output      string
+	CADir       string
+	Scope       []string
+	Fuzz        bool
+	Project     string
+	ScanName    string
+	ErrorCodes  []int
+	Threads     int
+	StorageURIs []string
+	Upstream    *url.URL
+	Client      *fuzzer.ClientProfile
+	Types       []string // Payload types injected by the fuzzed Tasks
+	Pipelines   payloads.Pipelines
+	Generators  payloads.Generators
+}
+
+// runProxy runs the intercepting proxy until it fails.
+// With Fuzz set every recorded request is also queued as a Task and fuzzed in the background.
+func runProxy(opts proxyOptions) error {
+	ca, err := proxy.LoadOrCreateCA(opts.CADir)
+	if err != nil {
+		return err
+	}
+	recorder, err := proxy.NewRecorder(opts.Output)
+	if err != nil {
+		return err
+	}
+	p := proxy.NewProxy(ca, proxy.Scope(opts.Scope), recorder)
+	if opts.Upstream != nil {
+		p.Transport.(*http.Transport).Proxy = http.ProxyURL(opts.Upstream)
+	}
+
+	if opts.Fuzz {
+		if len(opts.StorageURIs) == 0 {
+			return fmt.Errorf("--fuzz requires at least one storage URI (-C)")
+		}
+		queue := make(chan fuzzer.HTTPRequest, 100)
+		p.OnRequest = func(raw []byte, forceTLS bool) {
+			request, err := fuzzer.NewHTTPRequestFromBytes(raw, forceTLS)
+			if err != nil {
+				log.Printf("proxy queue error: %s\n", err)
+				return
+			}
+			select {
+			case queue <- request:
+			default:
+				log.Printf("proxy queue full, %s not queued\n", request.Request.URL)
+			}
+		}
+		go fuzzQueue(queue, opts)
+	}
+
+	fmt.Printf("CA certificate: %s\n", filepath.Join(opts.CADir, proxy.CACertFilename))
+	fmt.Printf("Recording requests to %s\n", opts.Output)
+	fmt.Printf("Proxy listening on %s\n", opts.Listen)
+	return http.ListenAndServe(opts.Listen, p)
+}
+
+// fuzzQueue runs a Task for each request received from queue.
+// Every Task is stored in its own file, named after the storage file and the Task.
+func fuzzQueue(queue <-chan fuzzer.HTTPRequest, opts proxyOptions) {
+	storageconfig := fuzzer.CreateStorageConfigFromURI(opts.StorageURIs)
+	i := 0
+	for request := range queue {
+		name := fmt.Sprintf("%s_%d", opts.ScanName, i)
+		i++
+		task, err := newTask(request, opts.Project, name, opts.ErrorCodes, opts.Client, opts.Types, opts.Pipelines, opts.Generators)
+		if err != nil {
+			fmt.Printf("Scan %s error: %s\n", name, err)
+			continue
+		}
+		taskconfig := storageconfig
+		if taskconfig.UseFile {
+			taskconfig.FileURI += "_" + name
+		}
+		task.Run(opts.Threads, taskconfig, opts.Upstream)
+	}
+}
@@ -0,0 +1,204 @@
+// Package proxy implements an intercepting HTTP(S) proxy that records the proxied requests as pandushi seeds.
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"path"
+	"strings"
+)
+
+// hopHeaders are removed from the requests before they are recorded and forwarded
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Upgrade",
+}
+
+// Scope is a list of host patterns like example.com or *.example.com.
+// An empty Scope contains every host.
+type Scope []string
+
+// Contains reports whether host matches one of the patterns of the Scope
+func (s Scope) Contains(host string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	host = strings.ToLower(hostname(host))
+	for _, pattern := range s {
+		pattern = strings.ToLower(pattern)
+		if pattern == host {
+			return true
+		}
+		if matched, _ := path.Match(pattern, host); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// hostname strips the port from host
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// Proxy is an intercepting HTTP proxy.
+// CONNECT requests are intercepted with certificates signed by CA.
+type Proxy struct {
+	CA        *CA
+	Scope     Scope
+	Recorder  Recorder
+	OnRequest func(raw []byte, forceTLS bool) // Optional callback for every in scope request
+	Transport http.RoundTripper
+}
+
+// NewProxy takes a CA, a Scope and a Recorder and returns a Proxy forwarding requests with the default transport
+func NewProxy(ca *CA, scope Scope, recorder Recorder) *Proxy {
+	return &Proxy{
+		CA:       ca,
+		Scope:    scope,
+		Recorder: recorder,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+}
+
+// ServeHTTP handles plain HTTP proxy requests and CONNECT tunnels
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.handleConnect(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "pandushi proxy only serves proxy requests", http.StatusBadRequest)
+		return
+	}
+	resp, err := p.roundTrip(r, false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for k, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// handleConnect hijacks the connection of a CONNECT request and serves the tunneled requests over TLS
+func (p *Proxy) handleConnect(w http.ResponseWriter, r *http.Request) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		log.Printf("Proxy.handleConnect Hijack error: %s\n", err)
+		return
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+	if err != nil {
+		return
+	}
+
+	tlsConn := tls.Server(conn, &tls.Config{
+		NextProtos: []string{"http/1.1"},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			name := hello.ServerName
+			if name == "" {
+				name = hostname(r.Host)
+			}
+			return p.CA.CertificateFor(name)
+		},
+	})
+	defer tlsConn.Close()
+	err = tlsConn.Handshake()
+	if err != nil {
+		log.Printf("Proxy.handleConnect %s handshake error: %s\n", r.Host, err)
+		return
+	}
+
+	reader := bufio.NewReader(tlsConn)
+	for {
+		req, err := http.ReadRequest(reader)
+		if err != nil {
+			return
+		}
+		req.URL.Scheme = "https"
+		req.URL.Host = r.Host
+		resp, err := p.roundTrip(req, true)
+		if err != nil {
+			resp = &http.Response{
+				StatusCode: http.StatusBadGateway,
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Header:     http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+				Body:       ioutil.NopCloser(strings.NewReader(err.Error())),
+			}
+		}
+		err = resp.Write(tlsConn)
+		resp.Body.Close()
+		if err != nil || req.Close || resp.Close {
+			return
+		}
+	}
+}
+
+// roundTrip records req when its host is in scope then forwards it
+func (p *Proxy) roundTrip(req *http.Request, forceTLS bool) (*http.Response, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.TransferEncoding = nil
+	req.RequestURI = ""
+	for _, h := range hopHeaders {
+		req.Header.Del(h)
+	}
+	if len(body) > 0 {
+		req.Header.Del("Transfer-Encoding")
+	}
+
+	if p.Scope.Contains(req.URL.Host) {
+		raw, err := httputil.DumpRequest(req, true)
+		if err != nil {
+			log.Printf("Proxy.roundTrip DumpRequest error: %s\n", err)
+		} else {
+			if p.Recorder != nil {
+				err = p.Recorder.Record(raw, forceTLS)
+				if err != nil {
+					log.Printf("Proxy.roundTrip Record error: %s\n", err)
+				}
+			}
+			if p.OnRequest != nil {
+				p.OnRequest(raw, forceTLS)
+			}
+		}
+	}
+	return p.Transport.RoundTrip(req)
+}
@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gi0cann/pandushi/importer"
+)
+
+func TestScopeContains(t *testing.T) {
+	tests := []struct {
+		scope    Scope
+		host     string
+		expected bool
+	}{
+		{Scope{}, "example.com", true},
+		{Scope{"example.com"}, "example.com:443", true},
+		{Scope{"example.com"}, "api.example.com", false},
+		{Scope{"*.example.com"}, "api.Example.com", true},
+		{Scope{"*.example.com"}, "example.org", false},
+		{Scope{"example.org", "127.0.0.1"}, "127.0.0.1:8080", true},
+	}
+	for _, test := range tests {
+		if got := test.scope.Contains(test.host); got != test.expected {
+			t.Errorf("Expected %v.Contains(%s) to be %v got %v\n", test.scope, test.host, test.expected, got)
+		}
+	}
+}
+
+func TestProxyRecordsRequests(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte("hello " + r.URL.Query().Get("name") + string(body)))
+	})
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+
+	dir, err := ioutil.TempDir("", "pandushi-proxy")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	ca, err := LoadOrCreateCA(dir)
+	if err != nil {
+		t.Fatalf("Error creating CA: %s\n", err)
+	}
+	if _, err := LoadCA(dir); err != nil {
+		t.Errorf("Expected the saved CA to load got %s\n", err)
+	}
+	seeds := filepath.Join(dir, "seeds.jsonl")
+	p := NewProxy(ca, Scope{"127.0.0.1"}, &JSONLRecorder{Filename: seeds})
+	var queued int
+	p.OnRequest = func(raw []byte, forceTLS bool) { queued++ }
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	proxyURL, _ := url.Parse(server.URL)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Certificate)
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: secure.Client().Transport.(*http.Transport).TLSClientConfig.Clone(),
+	}}
+	client.Transport.(*http.Transport).TLSClientConfig.RootCAs = roots
+
+	resp, err := client.Get(plain.URL + "/plain?name=foo")
+	if err != nil {
+		t.Fatalf("Error sending plain request: %s\n", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "hello foo" {
+		t.Errorf("Expected plain response body hello foo got %q\n", body)
+	}
+
+	resp, err = client.Post(secure.URL+"/secure?name=bar", "text/plain", strings.NewReader(" baz"))
+	if err != nil {
+		t.Fatalf("Error sending TLS request: %s\n", err)
+	}
+	body, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "hello bar baz" {
+		t.Errorf("Expected TLS response body hello bar baz got %q\n", body)
+	}
+
+	// out of scope requests are forwarded without being recorded
+	resp, err = client.Get(strings.Replace(plain.URL, "127.0.0.1", "localhost", 1) + "/ignored")
+	if err != nil {
+		t.Fatalf("Error sending out of scope request: %s\n", err)
+	}
+	resp.Body.Close()
+
+	requests, err := importer.NewHTTPRequestsFromList(seeds, false)
+	if err != nil {
+		t.Fatalf("Error loading recorded seeds: %s\n", err)
+	}
+	if len(requests) != 2 || queued != 2 {
+		t.Fatalf("Expected 2 recorded and queued requests got %d and %d\n", len(requests), queued)
+	}
+	if requests[0].ForceTLS || requests[0].Request.URL.String() != plain.URL+"/plain?name=foo" {
+		t.Errorf("Unexpected plain seed %s\n", requests[0].Request.URL)
+	}
+	if !requests[1].ForceTLS || requests[1].Request.Method != http.MethodPost || !strings.Contains(requests[1].RequestText, "\r\n\r\n baz") {
+		t.Errorf("Unexpected TLS seed %q\n", requests[1].RequestText)
+	}
+}
+
+func TestDirRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pandushi-seeds")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	recorder, err := NewRecorder(dir)
+	if err != nil {
+		t.Fatalf("Error creating recorder: %s\n", err)
+	}
+	for _, path := range []string{"/a", "/b"} {
+		err = recorder.Record([]byte("GET "+path+" HTTP/1.1\r\nHost: example.com\r\n\r\n"), false)
+		if err != nil {
+			t.Fatalf("Error recording request: %s\n", err)
+		}
+	}
+	requests, err := importer.NewHTTPRequestsFromDirectory(dir, false)
+	if err != nil {
+		t.Fatalf("Error loading recorded seeds: %s\n", err)
+	}
+	if len(requests) != 2 || requests[1].Request.URL.Path != "/b" {
+		t.Errorf("Expected the seeds to be loaded in recording order\n")
+	}
+}
@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gi0cann/pandushi/importer"
+)
+
+// Recorder saves raw proxied requests as seeds
+type Recorder interface {
+	Record(raw []byte, forceTLS bool) error
+}
+
+// DirRecorder writes each request to a numbered .req file of Dir.
+// The scheme isn't part of a .req file, HTTPS seeds have to be loaded with --force-tls.
+type DirRecorder struct {
+	Dir   string
+	mutex sync.Mutex
+	count int
+}
+
+// NewDirRecorder returns a DirRecorder numbering requests after the .req files already in dir
+func NewDirRecorder(dir string) (*DirRecorder, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := filepath.Glob(filepath.Join(dir, "*.req"))
+	if err != nil {
+		return nil, err
+	}
+	return &DirRecorder{Dir: dir, count: len(existing)}, nil
+}
+
+// Record writes raw to the next .req file
+func (d *DirRecorder) Record(raw []byte, forceTLS bool) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for {
+		d.count++
+		filename := filepath.Join(d.Dir, fmt.Sprintf("%06d.req", d.count))
+		fd, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, err = fd.Write(raw)
+		if err != nil {
+			fd.Close()
+			return err
+		}
+		return fd.Close()
+	}
+}
+
+// JSONLRecorder appends each request as a importer.RequestRecord line to a JSONL request list
+type JSONLRecorder struct {
+	Filename string
+	mutex    sync.Mutex
+}
+
+// Record appends raw to the request list
+func (j *JSONLRecorder) Record(raw []byte, forceTLS bool) error {
+	line, err := json.Marshal(importer.RequestRecord{Request: string(raw), ForceTLS: forceTLS})
+	if err != nil {
+		return err
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	fd, err := os.OpenFile(j.Filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = fd.Write(append(line, '\n'))
+	if err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+// NewRecorder returns a JSONLRecorder appending to output when it ends with .jsonl and a DirRecorder otherwise
+func NewRecorder(output string) (Recorder, error) {
+	if strings.HasSuffix(output, ".jsonl") {
+		return &JSONLRecorder{Filename: output}, nil
+	}
+	return NewDirRecorder(output)
+}
@@ -0,0 +1,157 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"os"
+)
+
+// htmlTemplate is a self-contained page, every style and script is inlined so the report works offline
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Pandushi report - {{.Task.Project}} / {{.Task.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; color: #222; }
+h1 { font-size: 1.4em; }
+h2 { font-size: 1.15em; margin-top: 1.5em; }
+table { border-collapse: collapse; font-size: 0.9em; }
+th, td { border: 1px solid #ccc; padding: 3px 8px; text-align: left; vertical-align: top; }
+th { background: #eee; }
+#testcases th { cursor: pointer; user-select: none; }
+#testcases th.asc::after { content: " \25B2"; }
+#testcases th.desc::after { content: " \25BC"; }
+#testcases tbody tr { cursor: pointer; }
+#testcases tbody tr:hover { background: #f5f5ff; }
+#testcases tbody tr.selected { background: #dde; }
+.summary { display: flex; gap: 2em; flex-wrap: wrap; }
+.viewer { display: flex; gap: 1em; margin-top: 1em; }
+.viewer div { flex: 1; min-width: 0; }
+pre { background: #f7f7f7; border: 1px solid #ccc; padding: 0.5em; white-space: pre-wrap; word-break: break-all; max-height: 40em; overflow: auto; }
+.CRITICAL, .HIGH { color: #b00; font-weight: bold; }
+.MEDIUM { color: #c60; }
+.LOW { color: #880; }
+#filter { margin-bottom: 0.5em; width: 30em; }
+</style>
+</head>
+<body>
+<h1>Pandushi report</h1>
+<table>
+<tr><th>Project</th><td>{{.Task.Project}}</td></tr>
+<tr><th>Scan</th><td>{{.Task.Name}}</td></tr>
+<tr><th>Start</th><td>{{.Task.Start}}</td></tr>
+<tr><th>End</th><td>{{.Task.End}}</td></tr>
+<tr><th>Test cases</th><td>{{len .Rows}}</td></tr>
+<tr><th>Findings</th><td>{{len .Task.Findings}}</td></tr>
+</table>
+<h2>Base request</h2>
+<pre>{{.Task.BaseRequest}}</pre>
+<h2>Summary</h2>
+<div class="summary">
+<table><tr><th>Injection type</th><th>Test cases</th></tr>
+{{range .ByInjectionType}}<tr><td>{{.Key}}</td><td>{{.Total}}</td></tr>
+{{end}}</table>
+<table><tr><th>Injection point type</th><th>Test cases</th></tr>
+{{range .ByInjectionPointType}}<tr><td>{{.Key}}</td><td>{{.Total}}</td></tr>
+{{end}}</table>
+<table><tr><th>Severity</th><th>Findings</th></tr>
+{{range .BySeverity}}<tr><td class="{{.Key}}">{{.Key}}</td><td>{{.Total}}</td></tr>
+{{end}}</table>
+</div>
+{{if .Task.Findings}}<h2>Findings</h2>
+<table>
+<tr><th>Severity</th><th>Class</th><th>Confidence</th><th>Point type</th><th>Point</th><th>Test cases</th><th>Evidence</th></tr>
+{{range .Task.Findings}}<tr><td class="{{.Severity}}">{{.Severity}}</td><td>{{.VulnerabilityClass}}</td><td>{{.Confidence}}</td><td>{{.InjectionPointType}}</td><td>{{.InjectionPoint}}</td><td>{{range .TestCases}}<a href="#" onclick="show({{.}}); return false;">#{{.}}</a> {{end}}</td><td>{{range .Evidence}}<pre>{{.}}</pre>{{end}}</td></tr>
+{{end}}</table>
+{{end}}<h2>Test cases</h2>
+<input id="filter" type="search" placeholder="Filter test cases">
+<table id="testcases">
+<thead><tr><th data-key="index" data-numeric="1">#</th><th data-key="injectiontype">Injection type</th><th data-key="injectionpointtype">Point type</th><th data-key="injectionpoint">Point</th><th data-key="injection">Injection</th><th data-key="statuscode" data-numeric="1">Status</th><th data-key="length" data-numeric="1">Length</th><th data-key="duration">Duration</th></tr></thead>
+<tbody></tbody>
+</table>
+<div class="viewer">
+<div><h2>Request <span id="current"></span></h2><pre id="request"></pre></div>
+<div><h2>Response</h2><pre id="response"></pre></div>
+</div>
+<script>
+var rows = {{.Rows}} || [];
+var sortKey = "index", sortDir = 1;
+var tbody = document.querySelector("#testcases tbody");
+var filter = document.getElementById("filter");
+
+function render() {
+	var q = filter.value.toLowerCase();
+	var visible = rows.filter(function (r) {
+		return q === "" || [r.injection, r.injectiontype, r.injectionpoint, r.injectionpointtype, String(r.statuscode)].join(" ").toLowerCase().indexOf(q) >= 0;
+	});
+	visible.sort(function (a, b) {
+		var x = a[sortKey], y = b[sortKey];
+		if (x < y) { return -sortDir; }
+		if (x > y) { return sortDir; }
+		return a.index - b.index;
+	});
+	tbody.textContent = "";
+	visible.forEach(function (r) {
+		var tr = document.createElement("tr");
+		tr.id = "tc" + r.index;
+		[r.index, r.injectiontype, r.injectionpointtype, r.injectionpoint, r.injection, r.statuscode, r.length, r.duration].forEach(function (v) {
+			var td = document.createElement("td");
+			td.textContent = v;
+			tr.appendChild(td);
+		});
+		tr.onclick = function () { show(r.index); };
+		tbody.appendChild(tr);
+	});
+}
+
+function show(index) {
+	var r = rows[index];
+	if (!r) { return; }
+	document.getElementById("current").textContent = "#" + index;
+	document.getElementById("request").textContent = r.request;
+	document.getElementById("response").textContent = r.response;
+	var prev = tbody.querySelector("tr.selected");
+	if (prev) { prev.className = ""; }
+	var tr = document.getElementById("tc" + index);
+	if (tr) { tr.className = "selected"; }
+	document.getElementById("request").scrollIntoView();
+}
+
+document.querySelectorAll("#testcases th").forEach(function (th) {
+	th.onclick = function () {
+		var key = th.getAttribute("data-key");
+		sortDir = key === sortKey ? -sortDir : 1;
+		sortKey = key;
+		document.querySelectorAll("#testcases th").forEach(function (h) { h.className = ""; });
+		th.className = sortDir === 1 ? "asc" : "desc";
+		render();
+	};
+});
+filter.oninput = render;
+render();
+</script>
+</body>
+</html>
+`
+
+var reportTemplate = template.Must(template.New("report").Parse(htmlTemplate))
+
+// WriteHTML renders the Summary as a self-contained HTML document
+func WriteHTML(w io.Writer, summary Summary) error {
+	return reportTemplate.Execute(w, summary)
+}
+
+// HTMLToFile renders the Summary as a self-contained HTML document and writes it to filename
+func HTMLToFile(filename string, summary Summary) error {
+	fd, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	err = WriteHTML(fd, summary)
+	if err != nil {
+		return err
+	}
+	return fd.Close()
+}
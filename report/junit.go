@@ -0,0 +1,123 @@
+package report
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gi0cann/pandushi/fuzzer"
+)
+
+// JUnitTestSuites is the root element of a JUnit XML report
+type JUnitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitTestSuite groups the injection points of an injection point type
+type JUnitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	TestCases []JUnitTestCase `xml:"testcase"`
+}
+
+// JUnitTestCase is an injection point
+type JUnitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Failures  []JUnitFailure `xml:"failure,omitempty"`
+}
+
+// JUnitFailure is a finding reported against an injection point
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// NewJUnit converts a stored task and its findings into a JUnit report.
+// Every injection point is a test case which fails when it has findings with a severity of at least minSeverity.
+func NewJUnit(task fuzzer.SerializedTask, minSeverity string) JUnitTestSuites {
+	suites := JUnitTestSuites{Name: task.Project + "/" + task.Name}
+	suiteIndex := make(map[string]int)
+	caseIndex := make(map[string][2]int)
+
+	addPoint := func(p Point) [2]int {
+		if i, ok := caseIndex[p.ID()]; ok {
+			return i
+		}
+		si, ok := suiteIndex[p.Type]
+		if !ok {
+			suites.Suites = append(suites.Suites, JUnitTestSuite{
+				Name:      task.Project + "/" + task.Name + "/" + p.Type,
+				Timestamp: task.Start.Format("2006-01-02T15:04:05"),
+			})
+			si = len(suites.Suites) - 1
+			suiteIndex[p.Type] = si
+		}
+		suite := &suites.Suites[si]
+		suite.TestCases = append(suite.TestCases, JUnitTestCase{Name: p.ID(), ClassName: "pandushi." + p.Type})
+		suite.Tests++
+		suites.Tests++
+		i := [2]int{si, len(suite.TestCases) - 1}
+		caseIndex[p.ID()] = i
+		return i
+	}
+
+	for _, p := range Points(task) {
+		addPoint(p)
+	}
+
+	for _, f := range FailingFindings(task, minSeverity) {
+		i := addPoint(pointOf(f.InjectionPointType, f.InjectionPoint))
+		suite := &suites.Suites[i[0]]
+		tc := &suite.TestCases[i[1]]
+		if len(tc.Failures) == 0 {
+			suite.Failures++
+			suites.Failures++
+		}
+		tc.Failures = append(tc.Failures, JUnitFailure{
+			Message: f.VulnerabilityClass + " (" + f.Severity + ", " + f.Confidence + ")",
+			Type:    f.VulnerabilityClass,
+			Text:    strings.Join(f.Evidence, "\n----\n"),
+		})
+	}
+
+	return suites
+}
+
+// WriteJUnit writes a stored task and its findings as JUnit XML
+func WriteJUnit(w io.Writer, task fuzzer.SerializedTask, minSeverity string) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "    ")
+	err = enc.Encode(NewJUnit(task, minSeverity))
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// JUnitToFile writes a stored task and its findings as JUnit XML to filename
+func JUnitToFile(filename string, task fuzzer.SerializedTask, minSeverity string) error {
+	fd, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	err = WriteJUnit(fd, task, minSeverity)
+	if err != nil {
+		return err
+	}
+	return fd.Close()
+}
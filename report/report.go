@@ -0,0 +1,148 @@
+// Package report renders stored fuzzer tasks into shareable formats
+package report
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gi0cann/pandushi/fuzzer"
+)
+
+// Count is the number of test cases sharing a key
+type Count struct {
+	Key   string
+	Total int
+}
+
+// Row is a flattened test case used by the report formats
+type Row struct {
+	Index              int    `json:"index"`
+	Injection          string `json:"injection"`
+	InjectionType      string `json:"injectiontype"`
+	InjectionPoint     string `json:"injectionpoint"`
+	InjectionPointType string `json:"injectionpointtype"`
+	StatusCode         int    `json:"statuscode"`
+	Length             int    `json:"length"`
+	Duration           string `json:"duration"`
+	Request            string `json:"request"`
+	Response           string `json:"response"`
+}
+
+// Summary contains the aggregated information about a stored task
+type Summary struct {
+	Task                 fuzzer.SerializedTask
+	ByInjectionType      []Count
+	ByInjectionPointType []Count
+	BySeverity           []Count
+	Rows                 []Row
+}
+
+// StatusCode returns the status code from the status line of a response text or 0 if it can't be parsed
+func StatusCode(responseText string) int {
+	line := strings.SplitN(responseText, "\r\n", 2)[0]
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return 0
+	}
+	code, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0
+	}
+	return code
+}
+
+func sortedCounts(counts map[string]int) []Count {
+	var result []Count
+	for k, v := range counts {
+		result = append(result, Count{Key: k, Total: v})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total == result[j].Total {
+			return result[i].Key < result[j].Key
+		}
+		return result[i].Total > result[j].Total
+	})
+	return result
+}
+
+// Summarize takes a SerializedTask and returns its Summary
+func Summarize(task fuzzer.SerializedTask) Summary {
+	summary := Summary{Task: task}
+	byInjectionType := make(map[string]int)
+	byInjectionPointType := make(map[string]int)
+	for i, tc := range task.TestCases {
+		byInjectionType[strings.ToUpper(tc.InjectionType)]++
+		byInjectionPointType[strings.ToUpper(tc.InjectionPointType)]++
+		summary.Rows = append(summary.Rows, Row{
+			Index:              i,
+			Injection:          tc.Injection,
+			InjectionType:      tc.InjectionType,
+			InjectionPoint:     tc.InjectionPoint,
+			InjectionPointType: tc.InjectionPointType,
+			StatusCode:         StatusCode(tc.Response),
+			Length:             len(tc.Response),
+			Duration:           tc.Duration,
+			Request:            tc.Request,
+			Response:           tc.Response,
+		})
+	}
+	bySeverity := make(map[string]int)
+	for _, f := range task.Findings {
+		bySeverity[f.Severity]++
+	}
+	summary.ByInjectionType = sortedCounts(byInjectionType)
+	summary.ByInjectionPointType = sortedCounts(byInjectionPointType)
+	for i := len(fuzzer.Severities) - 1; i >= 0; i-- {
+		summary.BySeverity = append(summary.BySeverity, Count{Key: fuzzer.Severities[i], Total: bySeverity[fuzzer.Severities[i]]})
+	}
+	return summary
+}
+
+// Point is an injection point of a stored task
+type Point struct {
+	Type      string
+	Name      string
+	TestCases int
+}
+
+// ID returns the identifier of the injection point used as SARIF rule id and JUnit test case name
+func (p Point) ID() string {
+	if p.Name == "" {
+		return p.Type
+	}
+	return p.Type + "/" + p.Name
+}
+
+func pointOf(injectionPointType string, injectionPoint string) Point {
+	return Point{Type: strings.ToUpper(injectionPointType), Name: injectionPoint}
+}
+
+// Points returns the injection points of a stored task in the order they were first tested
+func Points(task fuzzer.SerializedTask) []Point {
+	var points []Point
+	index := make(map[string]int)
+	for _, tc := range task.TestCases {
+		p := pointOf(tc.InjectionPointType, tc.InjectionPoint)
+		i, ok := index[p.ID()]
+		if !ok {
+			points = append(points, p)
+			i = len(points) - 1
+			index[p.ID()] = i
+		}
+		points[i].TestCases++
+	}
+	return points
+}
+
+// FailingFindings returns the findings of a stored task whose severity is at least minSeverity
+func FailingFindings(task fuzzer.SerializedTask, minSeverity string) []fuzzer.SerializedFinding {
+	var findings []fuzzer.SerializedFinding
+	min := fuzzer.SeverityRank(minSeverity)
+	for _, f := range task.Findings {
+		if fuzzer.SeverityRank(f.Severity) >= min {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
@@ -0,0 +1,127 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gi0cann/pandushi/fuzzer"
+)
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\nhello\r\n", 200},
+		{"HTTP/2.0 404 Not Found\r\n\r\n", 404},
+		{"", 0},
+		{"garbage", 0},
+	}
+
+	for _, tt := range tests {
+		if code := StatusCode(tt.input); code != tt.expected {
+			t.Errorf("StatusCode(%q) expected: %d got: %d\n", tt.input, tt.expected, code)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	task := fuzzer.SerializedTask{
+		Project: "default",
+		Name:    "default",
+		TestCases: []fuzzer.SerializedTestCase{
+			{Injection: "<script>alert(1)</script>", InjectionType: "XSS", InjectionPoint: "foo", InjectionPointType: "query", Response: "HTTP/1.1 200 OK\r\n\r\n<script>alert(1)</script>\r\n"},
+			{Injection: "<script>alert(1)</script>", InjectionType: "xss", InjectionPoint: "bar", InjectionPointType: "query"},
+			{Injection: "' or 1=1--", InjectionType: "SQLI", InjectionPoint: "User-Agent", InjectionPointType: "headers"},
+		},
+		Findings: []fuzzer.SerializedFinding{
+			{VulnerabilityClass: "XSS", Severity: fuzzer.SeverityHigh, TestCases: []int{0}},
+		},
+	}
+
+	summary := Summarize(task)
+
+	if len(summary.Rows) != 3 {
+		t.Fatalf("Expected 3 rows got %d\n", len(summary.Rows))
+	}
+	if summary.ByInjectionType[0].Key != "XSS" || summary.ByInjectionType[0].Total != 2 {
+		t.Errorf("Expected 2 XSS test cases got %+v\n", summary.ByInjectionType)
+	}
+	if summary.ByInjectionPointType[0].Key != "QUERY" || summary.ByInjectionPointType[0].Total != 2 {
+		t.Errorf("Expected 2 QUERY test cases got %+v\n", summary.ByInjectionPointType)
+	}
+	for _, c := range summary.BySeverity {
+		if c.Key == fuzzer.SeverityHigh && c.Total != 1 {
+			t.Errorf("Expected 1 HIGH finding got %d\n", c.Total)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := WriteHTML(&out, summary); err != nil {
+		t.Fatalf("WriteHTML error: %s\n", err)
+	}
+	if strings.Contains(out.String(), "<script>alert(1)</script>") {
+		t.Errorf("Payloads must be escaped in the HTML report\n")
+	}
+}
+
+func TestExports(t *testing.T) {
+	task := fuzzer.SerializedTask{
+		Project:     "default",
+		Name:        "default",
+		BaseRequest: "GET /test.php?foo=bar&id=1 HTTP/1.1\r\nHost: localhost:8009\r\n\r\n",
+		TestCases: []fuzzer.SerializedTestCase{
+			{Injection: "<script>alert(1)</script>", InjectionType: "XSS", InjectionPoint: "foo", InjectionPointType: "query", Request: "GET /test.php?foo=%3Cscript%3E&id=1 HTTP/1.1\r\nHost: localhost:8009\r\n\r\n", Response: "HTTP/1.1 200 OK\r\n\r\n<script>alert(1)</script>\r\n"},
+			{Injection: "<script>alert(1)</script>", InjectionType: "XSS", InjectionPoint: "id", InjectionPointType: "query"},
+			{Injection: "'", InjectionType: "SQLI", InjectionPoint: "id", InjectionPointType: "query", Response: "HTTP/1.1 500 Internal Server Error\r\n\r\n"},
+		},
+		Findings: []fuzzer.SerializedFinding{
+			{VulnerabilityClass: "XSS", Severity: fuzzer.SeverityHigh, Confidence: fuzzer.ConfidenceFirm, InjectionPoint: "foo", InjectionPointType: "query", TestCases: []int{0}},
+			{VulnerabilityClass: "SERVER_ERROR", Severity: fuzzer.SeverityLow, Confidence: fuzzer.ConfidenceTentative, InjectionPoint: "id", InjectionPointType: "query", TestCases: []int{2}},
+		},
+	}
+
+	sarif := NewSARIF(task)
+	rules := sarif.Runs[0].Tool.Driver.Rules
+	if len(rules) != 2 || rules[0].ID != "QUERY/foo" || rules[1].ID != "QUERY/id" {
+		t.Errorf("Expected a rule per injection point got %+v\n", rules)
+	}
+	results := sarif.Runs[0].Results
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 SARIF results got %d\n", len(results))
+	}
+	if results[0].RuleIndex != 0 || results[0].Level != "error" || results[1].RuleIndex != 1 || results[1].Level != "note" {
+		t.Errorf("SARIF results don't match their rules and levels: %+v\n", results)
+	}
+	if results[0].WebRequest == nil || results[0].WebRequest.Target != "http://localhost:8009/test.php?foo=%3Cscript%3E&id=1" {
+		t.Errorf("Unexpected SARIF web request %+v\n", results[0].WebRequest)
+	}
+	task.ForceTLS = true
+	results = NewSARIF(task).Runs[0].Results
+	if results[0].WebRequest == nil || results[0].WebRequest.Target != "https://localhost:8009/test.php?foo=%3Cscript%3E&id=1" || results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI != "https://localhost:8009/test.php?foo=bar&id=1" {
+		t.Errorf("Expected https targets for a TLS task got %+v %+v\n", results[0].WebRequest, results[0].Locations)
+	}
+	task.ForceTLS = false
+
+	tests := []struct {
+		minSeverity      string
+		expectedFailures int
+	}{
+		{fuzzer.SeverityInfo, 2},
+		{fuzzer.SeverityHigh, 1},
+		{fuzzer.SeverityCritical, 0},
+	}
+	for _, tt := range tests {
+		junit := NewJUnit(task, tt.minSeverity)
+		if junit.Tests != 2 {
+			t.Errorf("Expected 2 JUnit test cases got %d\n", junit.Tests)
+		}
+		if junit.Failures != tt.expectedFailures {
+			t.Errorf("Expected %d JUnit failures with minimum severity %s got %d\n", tt.expectedFailures, tt.minSeverity, junit.Failures)
+		}
+		if len(FailingFindings(task, tt.minSeverity)) != tt.expectedFailures {
+			t.Errorf("Expected %d failing findings with minimum severity %s\n", tt.expectedFailures, tt.minSeverity)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gi0cann/pandushi/fuzzer"
+)
+
+// SARIFVersion is the version of the SARIF specification produced by WriteSARIF
+const SARIFVersion = "2.1.0"
+
+// SARIFSchema is the JSON schema of the SARIF specification produced by WriteSARIF
+const SARIFSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// SARIFLog is the top level object of a SARIF 2.1 document
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+// SARIFRun is a single run of an analysis tool
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+// SARIFTool describes the analysis tool
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+// SARIFDriver describes the analysis tool component and the rules it checks
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+// SARIFRule describes an injection point checked by the fuzzer
+type SARIFRule struct {
+	ID               string                 `json:"id"`
+	ShortDescription SARIFMessage           `json:"shortDescription"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+// SARIFMessage is a plain text message
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFResult is a finding reported against a rule
+type SARIFResult struct {
+	RuleID      string                 `json:"ruleId"`
+	RuleIndex   int                    `json:"ruleIndex"`
+	Level       string                 `json:"level"`
+	Message     SARIFMessage           `json:"message"`
+	Locations   []SARIFLocation        `json:"locations,omitempty"`
+	WebRequest  *SARIFWebRequest       `json:"webRequest,omitempty"`
+	WebResponse *SARIFWebResponse      `json:"webResponse,omitempty"`
+	Properties  map[string]interface{} `json:"properties,omitempty"`
+}
+
+// SARIFLocation is the location of a result
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+// SARIFPhysicalLocation is the artifact a result was found in
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+}
+
+// SARIFArtifactLocation is the URI of an artifact
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SARIFWebRequest is the HTTP request that supports a result
+type SARIFWebRequest struct {
+	Target string `json:"target,omitempty"`
+	Method string `json:"method,omitempty"`
+}
+
+// SARIFWebResponse is the HTTP response that supports a result
+type SARIFWebResponse struct {
+	StatusCode int `json:"statusCode,omitempty"`
+}
+
+// SARIFLevel maps a finding severity to a SARIF result level
+func SARIFLevel(severity string) string {
+	switch strings.ToUpper(severity) {
+	case fuzzer.SeverityCritical, fuzzer.SeverityHigh:
+		return "error"
+	case fuzzer.SeverityMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// targetURL returns the method and URL of a stored request text, sent over TLS when forceTLS is set
+func targetURL(requestText string, forceTLS bool) (string, string) {
+	req, err := fuzzer.NewHTTPRequestFromBytes([]byte(requestText), forceTLS)
+	if err != nil {
+		return "", ""
+	}
+	return req.Request.Method, req.Request.URL.String()
+}
+
+// NewSARIF converts a stored task and its findings into a SARIF log.
+// Every injection point of the task is a rule and every finding a result of the rule of its injection point.
+func NewSARIF(task fuzzer.SerializedTask) SARIFLog {
+	driver := SARIFDriver{
+		Name:           "pandushi",
+		InformationURI: "https://github.com/gi0cann/pandushi",
+		Rules:          []SARIFRule{},
+	}
+	ruleIndex := make(map[string]int)
+	for _, p := range Points(task) {
+		ruleIndex[p.ID()] = len(driver.Rules)
+		driver.Rules = append(driver.Rules, SARIFRule{
+			ID:               p.ID(),
+			ShortDescription: SARIFMessage{Text: "Injection point " + p.ID()},
+			Properties: map[string]interface{}{
+				"injectionpointtype": p.Type,
+				"injectionpoint":     p.Name,
+				"testcases":          p.TestCases,
+			},
+		})
+	}
+
+	_, baseURL := targetURL(task.BaseRequest, task.ForceTLS)
+	results := []SARIFResult{}
+	for _, f := range task.Findings {
+		p := pointOf(f.InjectionPointType, f.InjectionPoint)
+		index, ok := ruleIndex[p.ID()]
+		if !ok {
+			index = len(driver.Rules)
+			ruleIndex[p.ID()] = index
+			driver.Rules = append(driver.Rules, SARIFRule{ID: p.ID(), ShortDescription: SARIFMessage{Text: "Injection point " + p.ID()}})
+		}
+		result := SARIFResult{
+			RuleID:    p.ID(),
+			RuleIndex: index,
+			Level:     SARIFLevel(f.Severity),
+			Message:   SARIFMessage{Text: f.VulnerabilityClass + " (" + f.Severity + ", " + f.Confidence + ") at " + p.ID()},
+			Properties: map[string]interface{}{
+				"vulnerabilityclass": f.VulnerabilityClass,
+				"severity":           f.Severity,
+				"confidence":         f.Confidence,
+				"evidence":           f.Evidence,
+				"testcases":          f.TestCases,
+			},
+		}
+		if baseURL != "" {
+			result.Locations = []SARIFLocation{{PhysicalLocation: SARIFPhysicalLocation{ArtifactLocation: SARIFArtifactLocation{URI: baseURL}}}}
+		}
+		if len(f.TestCases) > 0 && f.TestCases[0] < len(task.TestCases) {
+			tc := task.TestCases[f.TestCases[0]]
+			if method, target := targetURL(tc.Request, task.ForceTLS); target != "" {
+				result.WebRequest = &SARIFWebRequest{Target: target, Method: method}
+			}
+			if code := StatusCode(tc.Response); code != 0 {
+				result.WebResponse = &SARIFWebResponse{StatusCode: code}
+			}
+		}
+		results = append(results, result)
+	}
+
+	return SARIFLog{
+		Schema:  SARIFSchema,
+		Version: SARIFVersion,
+		Runs:    []SARIFRun{{Tool: SARIFTool{Driver: driver}, Results: results}},
+	}
+}
+
+// WriteSARIF writes a stored task and its findings as a SARIF 2.1 document
+func WriteSARIF(w io.Writer, task fuzzer.SerializedTask) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	return enc.Encode(NewSARIF(task))
+}
+
+// SARIFToFile writes a stored task and its findings as a SARIF 2.1 document to filename
+func SARIFToFile(filename string, task fuzzer.SerializedTask) error {
+	fd, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	err = WriteSARIF(fd, task)
+	if err != nil {
+		return err
+	}
+	return fd.Close()
+}